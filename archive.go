@@ -0,0 +1,217 @@
+package httplib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ArchiveRecord is one archived exchange as written by ArchiveTransport
+type ArchiveRecord struct {
+	Time           time.Time   `json:"time"`
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	RequestHeader  http.Header `json:"request_header"`
+	Status         int         `json:"status"`
+	ResponseHeader http.Header `json:"response_header"`
+	Body           []byte      `json:"body"`
+}
+
+// ArchiveSink stores encoded records under a unique name, e.g. a rotating
+// directory or an object store uploader
+type ArchiveSink interface {
+	Put(ctx context.Context, name string, data []byte) error
+}
+
+// DefaultArchiveRedact lists the headers ArchiveTransport redacts when Redact is nil
+var DefaultArchiveRedact = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// ArchiveTransport records every response, with metadata and body, to Sink for
+// retention of what third-party APIs returned. the body is buffered and handed
+// back unchanged. a failed write fails the request unless OnError is set, so
+// nothing goes unarchived silently. a nil Next uses http.DefaultTransport
+type ArchiveTransport struct {
+	Next http.RoundTripper
+	Sink ArchiveSink
+
+	// Redact names request and response headers whose values are replaced;
+	// nil uses the package Redaction. query secrets are masked by the latter either way
+	Redact []string
+
+	// RedactBody rewrites the archived copy of the body, e.g. to mask account
+	// numbers; nil masks the JSON and form fields of the package Redaction
+	RedactBody Transformer
+
+	// Compress names a registered codec, e.g. "gzip", to compress each record with
+	Compress string
+
+	// OnError receives write failures instead of failing the request
+	OnError func(err error)
+
+	seq uint64
+}
+
+// RoundTrip performs req and archives the response
+func (t *ArchiveTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := t.archive(req, resp, body); err != nil {
+		if t.OnError == nil {
+			return nil, fmt.Errorf("archiving response: %w", err)
+		}
+		t.OnError(err)
+	}
+	return resp, nil
+}
+
+func (t *ArchiveTransport) archive(req *http.Request, resp *http.Response, body []byte) error {
+	rec := ArchiveRecord{
+		Time:           time.Now().UTC(),
+		Method:         req.Method,
+		URL:            RedactURL(req.URL),
+		RequestHeader:  t.redact(req.Header),
+		Status:         resp.StatusCode,
+		ResponseHeader: t.redact(resp.Header),
+	}
+	if t.RedactBody == nil {
+		rec.Body = []byte(redactBody(resp.Header.Get("Content-Type"), body))
+	} else {
+		var err error
+		if rec.Body, err = t.RedactBody(append([]byte(nil), body...), rec.ResponseHeader); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	ext := ".json"
+	if t.Compress != "" {
+		if data, err = compressRecord(t.Compress, data); err != nil {
+			return err
+		}
+		ext += "." + t.Compress
+	}
+
+	seq := atomic.AddUint64(&t.seq, 1)
+	name := rec.Time.Format("20060102T150405.000000000Z") + "-" + strconv.FormatUint(seq, 10) + ext
+	return t.Sink.Put(req.Context(), name, data)
+}
+
+func (t *ArchiveTransport) redact(h http.Header) http.Header {
+	if t.Redact == nil {
+		return RedactHeaders(h)
+	}
+	return Redaction{Headers: t.Redact}.header(h)
+}
+
+func compressRecord(name string, data []byte) ([]byte, error) {
+	codec, ok := lookupCodec(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown codec %q", name)
+	}
+	var buf bytes.Buffer
+	w, err := codec.NewWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DirSink writes records into a directory per UTC day under Dir and removes
+// day directories older than MaxAge; zero MaxAge keeps everything
+type DirSink struct {
+	Dir    string
+	MaxAge time.Duration
+
+	mu      sync.Mutex
+	lastDay string
+}
+
+// archiveDayLayout names the day directories of a DirSink
+const archiveDayLayout = "2006-01-02"
+
+// Put writes data to Dir/<day>/name
+func (s *DirSink) Put(_ context.Context, name string, data []byte) error {
+	now := time.Now().UTC()
+	day := now.Format(archiveDayLayout)
+	dir := filepath.Join(s.Dir, day)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	rotated := s.lastDay != day
+	s.lastDay = day
+	s.mu.Unlock()
+	if rotated && s.MaxAge > 0 {
+		return s.prune(now)
+	}
+	return nil
+}
+
+// prune removes day directories that ended more than MaxAge before now
+func (s *DirSink) prune(now time.Time) error {
+	days, err := s.days()
+	if err != nil {
+		return err
+	}
+	for _, day := range days {
+		t, _ := time.Parse(archiveDayLayout, day)
+		if now.Sub(t.AddDate(0, 0, 1)) > s.MaxAge {
+			if err := os.RemoveAll(filepath.Join(s.Dir, day)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// days lists the day directories under Dir, oldest first
+func (s *DirSink) days() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var days []string
+	for _, e := range entries {
+		if _, err := time.Parse(archiveDayLayout, e.Name()); e.IsDir() && err == nil {
+			days = append(days, e.Name())
+		}
+	}
+	sort.Strings(days)
+	return days, nil
+}
@@ -0,0 +1,115 @@
+package httplib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/clairmont32/httplib/observe"
+)
+
+// BudgetExceededError is returned for requests cancelled by their profile's
+// latency Budget, so budget violations can be told apart from hard timeouts
+type BudgetExceededError struct {
+	Budget  time.Duration
+	Elapsed time.Duration
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("latency budget of %s exceeded after %s", e.Budget, e.Elapsed)
+}
+
+// budgetTimer cancels a request once its budget runs out.
+// it stays armed until the response body is closed
+type budgetTimer struct {
+	budget   time.Duration
+	start    time.Time
+	cancel   context.CancelFunc
+	timer    *time.Timer
+	exceeded int32
+	elapsed  time.Duration
+}
+
+// startBudget returns req bound to a context cancelled after budget
+func startBudget(req *http.Request, budget time.Duration) (*http.Request, *budgetTimer) {
+	ctx, cancel := context.WithCancel(req.Context())
+	b := &budgetTimer{budget: budget, start: time.Now(), cancel: cancel}
+	b.timer = time.AfterFunc(budget, func() {
+		b.elapsed = time.Since(b.start)
+		atomic.StoreInt32(&b.exceeded, 1)
+		cancel()
+	})
+	return req.WithContext(budgetContext{Context: ctx, b: b}), b
+}
+
+// budgetContext reports a budget cut as context.DeadlineExceeded rather than
+// context.Canceled, so it counts as a timeout and not as the caller giving up
+type budgetContext struct {
+	context.Context
+	b *budgetTimer
+}
+
+func (c budgetContext) Err() error {
+	if c.b.hit() {
+		return context.DeadlineExceeded
+	}
+	return c.Context.Err()
+}
+
+func (b *budgetTimer) hit() bool {
+	return atomic.LoadInt32(&b.exceeded) == 1
+}
+
+// stop disarms the budget and releases its context
+func (b *budgetTimer) stop() {
+	b.timer.Stop()
+	b.cancel()
+}
+
+// finish translates a cancellation by the budget into a *BudgetExceededError and
+// keeps the budget armed while the caller reads the body
+func (b *budgetTimer) finish(req *http.Request, resp *http.Response, err error, events *observe.EventBus) (*http.Response, error) {
+	if err != nil {
+		b.stop()
+		if b.hit() {
+			return nil, b.exceededError(req, events)
+		}
+		return nil, err
+	}
+	resp.Body = &budgetBody{ReadCloser: resp.Body, b: b, req: req, events: events}
+	return resp, nil
+}
+
+func (b *budgetTimer) exceededError(req *http.Request, events *observe.EventBus) error {
+	events.Publish(observe.BudgetExceeded{Time: time.Now(), Method: req.Method, URL: req.URL.String(), Budget: b.budget, Elapsed: b.elapsed})
+	return &BudgetExceededError{Budget: b.budget, Elapsed: b.elapsed}
+}
+
+// budgetBody reports a budget cut during the body read and disarms it on close
+type budgetBody struct {
+	io.ReadCloser
+	b      *budgetTimer
+	req    *http.Request
+	events *observe.EventBus
+	once   sync.Once
+	err    error
+}
+
+func (r *budgetBody) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && r.b.hit() {
+		r.once.Do(func() { r.err = r.b.exceededError(r.req, r.events) })
+		return n, r.err
+	}
+	return n, err
+}
+
+func (r *budgetBody) Close() error {
+	err := r.ReadCloser.Close()
+	r.b.stop()
+	return err
+}
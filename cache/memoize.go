@@ -0,0 +1,117 @@
+// Package cache holds application level caching built on httplib:
+// conditional fetching with persisted validators and memoized lookups
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Memoize wraps fn so results are cached per key for ttl at the application level,
+// e.g. device ID to device details. multi-argument lookups use a struct key.
+// errors are not cached, and concurrent calls for the same key wait for a single
+// call to fn instead of each hitting the API. a waiting call returns early with
+// ctx.Err() if its context ends first, and calls fn itself when the call it
+// waited on failed because that caller's context ended
+func Memoize[K comparable, V any](fn func(context.Context, K) (V, error), ttl time.Duration) func(context.Context, K) (V, error) {
+	m := &memo[K, V]{fn: fn, ttl: ttl, entries: make(map[K]*memoEntry[V])}
+	return m.call
+}
+
+type memo[K comparable, V any] struct {
+	fn  func(context.Context, K) (V, error)
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[K]*memoEntry[V]
+	lastSweep time.Time
+}
+
+// memoEntry is a cached or in-flight result; done is closed once val and err are set
+type memoEntry[V any] struct {
+	done    chan struct{}
+	val     V
+	err     error
+	expires time.Time
+	// canceled marks an err caused by the context of the caller that ran fn
+	canceled bool
+}
+
+func (m *memo[K, V]) call(ctx context.Context, key K) (V, error) {
+	now := time.Now()
+	m.mu.Lock()
+	m.sweep(now)
+	e, ok := m.entries[key]
+	if ok {
+		select {
+		case <-e.done:
+			if now.Before(e.expires) {
+				m.mu.Unlock()
+				return e.val, nil
+			}
+			ok = false
+		default:
+			// in flight
+		}
+	}
+	if !ok {
+		e = &memoEntry[V]{done: make(chan struct{})}
+		m.entries[key] = e
+		m.mu.Unlock()
+
+		m.run(ctx, key, e)
+		return e.val, e.err
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-e.done:
+		if e.canceled && ctx.Err() == nil {
+			// the call failed because its caller gave up, not for us; try again
+			return m.call(ctx, key)
+		}
+		return e.val, e.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// run calls fn for e and publishes the result, dropping failures so they are
+// retried. a panic in fn is treated as a failure so waiters are released
+func (m *memo[K, V]) run(ctx context.Context, key K, e *memoEntry[V]) {
+	e.err = errMemoPanicked
+	defer func() {
+		e.expires = time.Now().Add(m.ttl)
+		m.mu.Lock()
+		if e.err != nil && m.entries[key] == e {
+			delete(m.entries, key)
+		}
+		m.mu.Unlock()
+		close(e.done)
+	}()
+	e.val, e.err = m.fn(ctx, key)
+	e.canceled = e.err != nil && ctx.Err() != nil
+}
+
+var errMemoPanicked = errors.New("memoized function panicked")
+
+// sweep drops expired entries at most once per ttl so the map does not grow
+// without bound; m.mu must be held
+func (m *memo[K, V]) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < m.ttl {
+		return
+	}
+	m.lastSweep = now
+	for k, e := range m.entries {
+		select {
+		case <-e.done:
+			if !now.Before(e.expires) {
+				delete(m.entries, k)
+			}
+		default:
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/clairmont32/httplib"
+	"github.com/clairmont32/httplib/internal/jsonfile"
+)
+
+// Validators are the cache validators remembered for a URL between runs
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ValidatorStore persists Validators per URL for SyncFetcher
+type ValidatorStore interface {
+	Get(url string) (v Validators, ok bool, err error)
+	Set(url string, v Validators) error
+}
+
+// SyncFetcher performs conditional GETs and skips resources that did not change
+// since the validators in Store were recorded
+type SyncFetcher struct {
+	Store ValidatorStore
+
+	// Client performs the requests; nil uses httplib.DefaultClient
+	Client *httplib.NewClient
+}
+
+// Fetch performs a conditional GET of url.
+// changed is false and body nil when the server answered 304 Not Modified.
+// validators from a changed response are saved before returning
+func (s SyncFetcher) Fetch(ctx context.Context, url string) (body []byte, changed bool, err error) {
+	if s.Store == nil {
+		return nil, false, errors.New("SyncFetcher requires a Store")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	prev, ok, err := s.Store.Get(url)
+	if err != nil {
+		return nil, false, err
+	}
+	if ok {
+		if prev.ETag != "" {
+			req.Header.Set("If-None-Match", prev.ETag)
+		}
+		if prev.LastModified != "" {
+			req.Header.Set("If-Modified-Since", prev.LastModified)
+		}
+	}
+
+	resp, err := do(s.Client, req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		_ = httplib.DrainBody(resp)
+		return nil, false, nil
+	}
+
+	body, err = httplib.ProcessStatusCode(resp)
+	if err != nil {
+		return nil, false, err
+	}
+
+	next := Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if next != (Validators{}) {
+		if err := s.Store.Set(url, next); err != nil {
+			return body, true, err
+		}
+	}
+	return body, true, nil
+}
+
+// FetchChanged fetches each URL in turn and returns Results only for resources
+// that changed. it stops at the first error
+func (s SyncFetcher) FetchChanged(ctx context.Context, urls []string) ([]httplib.Result, error) {
+	var changed []httplib.Result
+	for _, u := range urls {
+		body, ok, err := s.Fetch(ctx, u)
+		if err != nil {
+			return changed, err
+		}
+		if ok {
+			changed = append(changed, httplib.Result{URL: u, Body: body})
+		}
+	}
+	return changed, nil
+}
+
+// MemoryValidatorStore keeps validators in memory; the zero value is ready to use
+type MemoryValidatorStore struct {
+	mu   sync.Mutex
+	data map[string]Validators
+}
+
+// Get returns the validators stored for url
+func (m *MemoryValidatorStore) Get(url string) (Validators, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.data[url]
+	return v, ok, nil
+}
+
+// Set stores the validators for url
+func (m *MemoryValidatorStore) Set(url string, v Validators) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		m.data = make(map[string]Validators)
+	}
+	m.data[url] = v
+	return nil
+}
+
+// FileValidatorStore keeps validators in a JSON file so they survive restarts.
+// the file is rewritten atomically on every Set
+type FileValidatorStore struct {
+	Path string
+
+	mu     sync.Mutex
+	data   map[string]Validators
+	loaded bool
+}
+
+// Get returns the validators stored for url
+func (f *FileValidatorStore) Get(url string) (Validators, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.load(); err != nil {
+		return Validators{}, false, err
+	}
+	v, ok := f.data[url]
+	return v, ok, nil
+}
+
+// Set stores the validators for url and persists the file
+func (f *FileValidatorStore) Set(url string, v Validators) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.load(); err != nil {
+		return err
+	}
+	f.data[url] = v
+
+	return jsonfile.Write(f.Path, f.data)
+}
+
+// load reads the file once; a missing file is an empty store
+func (f *FileValidatorStore) load() error {
+	if f.loaded {
+		return nil
+	}
+	f.data = make(map[string]Validators)
+	if err := jsonfile.Read(f.Path, &f.data); err != nil {
+		return err
+	}
+	f.loaded = true
+	return nil
+}
+
+// do performs req with c, or httplib.DefaultClient when c is nil
+func do(c *httplib.NewClient, req *http.Request) (*http.Response, error) {
+	if c == nil {
+		resp, _, err := httplib.DefaultClient(req)
+		return resp, err
+	}
+	resp, _, err := c.DoRequest(req)
+	return resp, err
+}
@@ -0,0 +1,121 @@
+package httplib
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// Clone returns a copy of r so templates can be modified per call without
+// sharing the Payload slice, PathParams or map-typed Query and Form values,
+// e.g. url.Values. struct values and pointers in Body, Data, Query and Form
+// are shared with r
+func (r FormRequest) Clone() FormRequest {
+	c := r
+	if r.Payload != nil {
+		c.Payload = append([]byte(nil), r.Payload...)
+	}
+	if r.PathParams != nil {
+		c.PathParams = make(map[string]string, len(r.PathParams))
+		for k, v := range r.PathParams {
+			c.PathParams[k] = v
+		}
+	}
+	c.Query = cloneFormValue(r.Query)
+	c.Form = cloneFormValue(r.Form)
+	return c
+}
+
+// cloneFormValue copies maps as accepted by EncodeForm; slices in url.Values
+// and map[string][]string are copied too, other values are returned as is
+func cloneFormValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case url.Values:
+		return url.Values(cloneStrings(v))
+	case map[string][]string:
+		return cloneStrings(v)
+	case map[string]string:
+		c := make(map[string]string, len(v))
+		for k, s := range v {
+			c[k] = s
+		}
+		return c
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.IsNil() {
+		return v
+	}
+	c := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		c.SetMapIndex(iter.Key(), iter.Value())
+	}
+	return c.Interface()
+}
+
+func cloneStrings(m map[string][]string) map[string][]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string][]string, len(m))
+	for k, vs := range m {
+		c[k] = append([]string(nil), vs...)
+	}
+	return c
+}
+
+// CloneRequest returns a deep copy of req, including headers, trailers and body.
+// the body is buffered once so the original and the clone can each be read
+// in full, and both get a GetBody for redirects and retries
+func CloneRequest(req *http.Request) (*http.Request, error) {
+	c := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return c, nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		c.Body = body
+		return c, nil
+	}
+
+	payload, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	getBody := func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(payload)), nil
+	}
+	req.Body, _ = getBody()
+	req.GetBody = getBody
+	c.Body, _ = getBody()
+	c.GetBody = getBody
+	return c, nil
+}
+
+// CloneResponse returns a deep copy of resp with its own header maps and body reader.
+// the body is read into memory and resp gets a fresh reader over the same bytes
+func CloneResponse(resp *http.Response) (*http.Response, error) {
+	c := *resp
+	c.Header = resp.Header.Clone()
+	c.Trailer = resp.Trailer.Clone()
+	if resp.Body == nil {
+		return &c, nil
+	}
+
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	c.Body = io.NopCloser(bytes.NewReader(body))
+	return &c, nil
+}
@@ -0,0 +1,193 @@
+package httplib
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Codec implements a Content-Encoding such as gzip or a service specific lz4
+type Codec interface {
+	// NewWriter returns a writer compressing into w; Close flushes it
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader returns a reader decompressing r
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{
+		"gzip":    gzipCodec{},
+		"deflate": deflateCodec{},
+	}
+)
+
+// RegisterCodec makes c available for the Content-Encoding token name.
+// gzip and deflate are registered by default; registering a name again replaces it
+func RegisterCodec(name string, c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[strings.ToLower(name)] = c
+}
+
+func lookupCodec(name string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[strings.ToLower(strings.TrimSpace(name))]
+	return c, ok
+}
+
+// acceptEncoding lists every registered codec for the Accept-Encoding header
+func acceptEncoding() string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// CompressionTransport applies registered codecs to traffic through Next.
+// responses using registered encodings are decoded transparently and request
+// bodies are encoded with the codec named by Send, if set.
+// a nil Next uses http.DefaultTransport
+type CompressionTransport struct {
+	Next http.RoundTripper
+	Send string
+}
+
+// RoundTrip encodes the request body, advertises the registered codecs and decodes the response
+func (t CompressionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	r := req.Clone(req.Context())
+	if r.Header.Get("Accept-Encoding") == "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding())
+	}
+	if t.Send != "" && r.Body != nil && r.Body != http.NoBody && r.Header.Get("Content-Encoding") == "" {
+		if err := encodeBody(r, t.Send); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := next.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeBody(resp); err != nil {
+		_ = DrainBody(resp)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// encodeBody replaces the body of r with its encoding under the named codec
+func encodeBody(r *http.Request, name string) error {
+	codec, ok := lookupCodec(name)
+	if !ok {
+		return fmt.Errorf("no codec registered for content encoding %q", name)
+	}
+
+	var buf bytes.Buffer
+	w, err := codec.NewWriter(&buf)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r.Body)
+	_ = r.Body.Close()
+	if err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	encoded := buf.Bytes()
+	r.Body = io.NopCloser(bytes.NewReader(encoded))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(encoded)), nil
+	}
+	r.ContentLength = int64(len(encoded))
+	r.Header.Set("Content-Encoding", name)
+	return nil
+}
+
+// decodeBody unwraps every encoding listed in Content-Encoding, last applied first.
+// responses using an unregistered encoding are returned untouched
+func decodeBody(resp *http.Response) error {
+	header := resp.Header.Get("Content-Encoding")
+	if header == "" || resp.Body == nil {
+		return nil
+	}
+
+	names := strings.Split(header, ",")
+	chain := make([]Codec, 0, len(names))
+	for i := len(names) - 1; i >= 0; i-- {
+		name := strings.TrimSpace(names[i])
+		if strings.EqualFold(name, "identity") {
+			continue
+		}
+		codec, ok := lookupCodec(name)
+		if !ok {
+			return nil
+		}
+		chain = append(chain, codec)
+	}
+
+	body := resp.Body
+	var reader io.Reader = body
+	closers := []io.Closer{body}
+	for _, codec := range chain {
+		rc, err := codec.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("decoding %s response: %w", header, err)
+		}
+		reader = rc
+		closers = append(closers, rc)
+	}
+
+	resp.Body = &decodedBody{Reader: reader, closers: closers}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// decodedBody closes every decoder and the underlying body
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (d *decodedBody) Close() error {
+	var first error
+	for i := len(d.closers) - 1; i >= 0; i-- {
+		if err := d.closers[i].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error)  { return gzip.NewReader(r) }
+
+// deflateCodec is HTTP "deflate", which is zlib framed per RFC 9110
+type deflateCodec struct{}
+
+func (deflateCodec) NewWriter(w io.Writer) (io.WriteCloser, error) { return zlib.NewWriter(w), nil }
+func (deflateCodec) NewReader(r io.Reader) (io.ReadCloser, error)  { return zlib.NewReader(r) }
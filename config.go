@@ -0,0 +1,247 @@
+package httplib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/clairmont32/httplib/internal/logging"
+	"github.com/clairmont32/httplib/ratelimit"
+)
+
+// Config is the part of a client's settings that can change at runtime
+//
+// on disk it is JSON with durations as strings and rate limits in requests
+// per second:
+//
+//	{"timeout": "5s", "base_url": "https://api-b.example.com", "headers": {"X-Tenant": "a"},
+//	 "rate_limit": {"rate": 10, "burst": 20}}
+type Config struct {
+	// Timeout replaces NewClient.Timeout when set; profile timeouts still win
+	Timeout time.Duration
+	// BaseURL replaces the scheme and host of every outgoing request when set
+	BaseURL string
+	// Headers are set on every outgoing request
+	Headers map[string]string
+	// Profiles are merged over NewClient.Profiles by name
+	Profiles map[string]Profile
+	// Limiter replaces NewClient.Limiter when set. a "rate_limit" read from
+	// JSON becomes a token bucket shared by all requests, starting full on
+	// every reload
+	Limiter ratelimit.RequestLimiter
+}
+
+type configJSON struct {
+	Timeout   string                 `json:"timeout,omitempty"`
+	BaseURL   string                 `json:"base_url,omitempty"`
+	Headers   map[string]string      `json:"headers,omitempty"`
+	Profiles  map[string]profileJSON `json:"profiles,omitempty"`
+	RateLimit *rateLimitJSON         `json:"rate_limit,omitempty"`
+}
+
+type rateLimitJSON struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst,omitempty"`
+}
+
+type profileJSON struct {
+	Timeout string            `json:"timeout,omitempty"`
+	Budget  string            `json:"budget,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// UnmarshalJSON decodes the on-disk form of Config
+func (c *Config) UnmarshalJSON(b []byte) error {
+	var raw configJSON
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	var cfg Config
+	var err error
+	if cfg.Timeout, err = parseOptionalDuration(raw.Timeout); err != nil {
+		return fmt.Errorf("timeout: %w", err)
+	}
+	cfg.BaseURL = raw.BaseURL
+	cfg.Headers = raw.Headers
+	if rl := raw.RateLimit; rl != nil {
+		if !(rl.Rate > 0) {
+			return fmt.Errorf("rate_limit: rate must be positive, got %v", rl.Rate)
+		}
+		cfg.Limiter = ratelimit.All{Limiter: ratelimit.NewTokenBucket(rl.Rate, rl.Burst)}
+	}
+	for name, p := range raw.Profiles {
+		timeout, err := parseOptionalDuration(p.Timeout)
+		if err != nil {
+			return fmt.Errorf("profile %s timeout: %w", name, err)
+		}
+		budget, err := parseOptionalDuration(p.Budget)
+		if err != nil {
+			return fmt.Errorf("profile %s budget: %w", name, err)
+		}
+		if cfg.Profiles == nil {
+			cfg.Profiles = make(map[string]Profile)
+		}
+		profile := Profile{Timeout: timeout, Budget: budget}
+		for k, v := range p.Headers {
+			profile.Headers = append(profile.Headers, Headers{Key: k, Value: v})
+		}
+		cfg.Profiles[name] = profile
+	}
+	*c = cfg
+	return nil
+}
+
+func parseOptionalDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// validate rejects configs that would break every request
+func (c Config) validate() error {
+	if c.Timeout < 0 {
+		return fmt.Errorf("negative timeout %s", c.Timeout)
+	}
+	if c.BaseURL != "" {
+		u, err := url.Parse(c.BaseURL)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("base url %q needs a scheme and host", c.BaseURL)
+		}
+	}
+	return nil
+}
+
+// LiveConfig holds a Config that can be replaced while clients are using it.
+// set it on NewClient.Live; every request reads the current value
+type LiveConfig struct {
+	v atomic.Value // Config
+}
+
+// NewLiveConfig returns a LiveConfig starting with cfg
+func NewLiveConfig(cfg Config) *LiveConfig {
+	l := &LiveConfig{}
+	l.v.Store(cfg)
+	return l
+}
+
+// Load returns the current config; callers must not modify its maps
+func (l *LiveConfig) Load() Config {
+	cfg, _ := l.v.Load().(Config)
+	return cfg
+}
+
+// Store validates cfg and makes it current for all following requests
+func (l *LiveConfig) Store(cfg Config) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	l.v.Store(cfg)
+	return nil
+}
+
+// LoadFile reads a JSON config file and makes it current
+func (l *LiveConfig) LoadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cfg Config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	return l.Store(cfg)
+}
+
+// defaultWatchInterval is used by Watch and WatchFile for intervals of zero or less
+const defaultWatchInterval = 30 * time.Second
+
+// Watch calls source every interval and stores what it returns until ctx is done.
+// a failing source or invalid config keeps the previous value and is logged.
+// an interval of zero or less uses 30s
+func (l *LiveConfig) Watch(ctx context.Context, interval time.Duration, source func() (Config, error)) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		cfg, err := source()
+		if err == nil {
+			err = l.Store(cfg)
+		}
+		if err != nil {
+			logging.Get().Warn("config reload failed, keeping previous", "error", err)
+		}
+	}
+}
+
+// WatchFile reloads path whenever its modification time changes, checking every
+// interval; zero or less uses 30s
+func (l *LiveConfig) WatchFile(ctx context.Context, path string, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	var last time.Time
+	if fi, err := os.Stat(path); err == nil {
+		last = fi.ModTime()
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			logging.Get().Warn("config reload failed, keeping previous", "error", err)
+			continue
+		}
+		if !fi.ModTime().After(last) {
+			continue
+		}
+		last = fi.ModTime()
+		if err := l.LoadFile(path); err != nil {
+			logging.Get().Warn("config reload failed, keeping previous", "error", err)
+		}
+	}
+}
+
+// apply returns req rewritten for the base URL and headers of cfg
+func (c Config) apply(req *http.Request) (*http.Request, error) {
+	if c.BaseURL == "" && len(c.Headers) == 0 {
+		return req, nil
+	}
+
+	r := req.Clone(req.Context())
+	if c.BaseURL != "" {
+		base, err := url.Parse(c.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+		r.URL.Scheme = base.Scheme
+		r.URL.Host = base.Host
+		r.Host = ""
+	}
+	for k, v := range c.Headers {
+		r.Header.Set(k, v)
+	}
+	return r, nil
+}
@@ -0,0 +1,13 @@
+package httplib
+
+// contextKey namespaces the per-request settings httplib stores in a request context
+type contextKey int
+
+const (
+	serverNameKey contextKey = iota
+	profileKey
+	tagsKey
+	retryKey
+	residencyKey
+	loggerKey
+)
@@ -0,0 +1,200 @@
+package httplib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Page is a fetched page handed to Crawler.Handle
+type Page struct {
+	URL    string
+	Depth  int
+	Header http.Header
+	Body   []byte
+	Err    error
+}
+
+// Crawler walks pages starting from Seeds, fetching each URL at most once
+// and never hitting the same host concurrently or more often than Delay allows
+type Crawler struct {
+	Seeds []string
+
+	// Handle receives every fetched page, including failed ones with Err set,
+	// and returns further URLs to crawl. relative links are resolved against the page.
+	// returning an error stops the crawl. with Concurrency above 1 it is called
+	// from several goroutines at once and must be safe for concurrent use
+	Handle func(Page) ([]string, error)
+
+	// Concurrency bounds requests in flight across all hosts; values below 1 mean 1
+	Concurrency int
+	// Delay is the minimum gap between the end of one request and the start of the next on a host
+	Delay time.Duration
+	// MaxDepth stops following links past this depth from the seeds; 0 means no limit
+	MaxDepth int
+	// MaxPages stops the crawl after this many fetches; 0 means no limit
+	MaxPages int
+
+	// Client performs the requests; nil uses the default client
+	Client *NewClient
+}
+
+// crawlJob is a frontier entry
+type crawlJob struct {
+	url   string
+	host  string
+	depth int
+}
+
+// crawlDone reports a finished fetch back to the scheduler
+type crawlDone struct {
+	job   crawlJob
+	links []string
+	err   error
+}
+
+// Run crawls until the frontier is empty, a limit is reached, Handle fails or ctx ends
+func (c *Crawler) Run(ctx context.Context) error {
+	if c.Handle == nil {
+		return errors.New("Crawler requires a Handle func")
+	}
+	workers := c.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		frontier []crawlJob
+		seen     = make(map[string]bool)
+		busy     = make(map[string]bool)
+		ready    = make(map[string]time.Time)
+		inflight int
+		fetched  int
+		done     = make(chan crawlDone)
+		firstErr error
+		ctxDone  = ctx.Done()
+	)
+
+	enqueue := func(raw string, base *url.URL, depth int) {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return
+		}
+		if base != nil {
+			u = base.ResolveReference(u)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return
+		}
+		u.Fragment = ""
+		key := u.String()
+		if seen[key] || (c.MaxDepth > 0 && depth > c.MaxDepth) {
+			return
+		}
+		seen[key] = true
+		frontier = append(frontier, crawlJob{url: key, host: u.Host, depth: depth})
+	}
+	for _, s := range c.Seeds {
+		enqueue(s, nil, 0)
+	}
+
+	for {
+		// dispatch every job whose host is idle and past its politeness delay
+		now := time.Now()
+		var wake time.Time
+		for i := 0; i < len(frontier) && inflight < workers && firstErr == nil; {
+			if c.MaxPages > 0 && fetched >= c.MaxPages {
+				frontier = nil
+				break
+			}
+			job := frontier[i]
+			if busy[job.host] {
+				i++
+				continue
+			}
+			if at := ready[job.host]; at.After(now) {
+				if wake.IsZero() || at.Before(wake) {
+					wake = at
+				}
+				i++
+				continue
+			}
+
+			frontier = append(frontier[:i], frontier[i+1:]...)
+			busy[job.host] = true
+			inflight++
+			fetched++
+			go func() {
+				links, err := c.visit(ctx, job)
+				done <- crawlDone{job: job, links: links, err: err}
+			}()
+		}
+
+		if inflight == 0 && (len(frontier) == 0 || firstErr != nil) {
+			return firstErr
+		}
+
+		var (
+			timer *time.Timer
+			wakeC <-chan time.Time
+		)
+		if !wake.IsZero() && inflight < workers {
+			timer = time.NewTimer(time.Until(wake))
+			wakeC = timer.C
+		}
+
+		select {
+		case d := <-done:
+			inflight--
+			busy[d.job.host] = false
+			ready[d.job.host] = time.Now().Add(c.Delay)
+			if d.err != nil {
+				if firstErr == nil {
+					firstErr = d.err
+					cancel()
+				}
+				continue
+			}
+			base, _ := url.Parse(d.job.url)
+			for _, l := range d.links {
+				enqueue(l, base, d.job.depth+1)
+			}
+		case <-wakeC:
+		case <-ctxDone:
+			ctxDone = nil // only wait for in-flight fetches from here on
+			if firstErr == nil {
+				firstErr = ctx.Err()
+			}
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// visit fetches one page and hands it to Handle
+func (c *Crawler) visit(ctx context.Context, job crawlJob) ([]string, error) {
+	page := Page{URL: job.url, Depth: job.depth}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, job.url, nil)
+	if err != nil {
+		page.Err = err
+		return c.Handle(page)
+	}
+	resp, err := doWith(c.Client, req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		page.Err = err
+		return c.Handle(page)
+	}
+	page.Header = resp.Header
+	page.Body, page.Err = ProcessStatusCode(resp)
+	return c.Handle(page)
+}
@@ -0,0 +1,195 @@
+package httplib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // bit sets of allowed values
+	domAny, dowAny                bool
+	every                         time.Duration
+}
+
+// cronField describes the value range of one cron field
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+var cronAliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseSchedule parses a standard five field cron expression
+// (minute hour day-of-month month day-of-week) supporting *, lists, ranges
+// and steps, the @hourly style aliases and "@every <duration>".
+// as in cron, when both day fields are restricted either may match; a day
+// field covering its whole range, e.g. "*/1" or "0-6", counts as unrestricted
+func ParseSchedule(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, "@every ")))
+		if err != nil || d <= 0 {
+			return Schedule{}, fmt.Errorf("cron %q: invalid interval", expr)
+		}
+		return Schedule{every: d}, nil
+	}
+	if alias, ok := cronAliases[expr]; ok {
+		expr = alias
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return Schedule{}, fmt.Errorf("cron %q: want 5 fields, got %d", expr, len(fields))
+	}
+
+	var sets [5]uint64
+	for i, f := range fields {
+		set, err := parseCronField(f, cronFields[i])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("cron %q: %w", expr, err)
+		}
+		sets[i] = set
+	}
+	// 7 is also Sunday
+	if sets[4]&(1<<7) != 0 {
+		sets[4] = sets[4]&^(1<<7) | 1
+	}
+	return Schedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: sets[2] == fullCronSet(cronFields[2]),
+		dowAny: sets[4] == fullCronSet(cronFields[4]),
+	}, nil
+}
+
+// fullCronSet is the bit set of every value of f
+func fullCronSet(f cronField) uint64 {
+	return (1<<uint(f.max+1) - 1) &^ (1<<uint(f.min) - 1)
+}
+
+func parseCronField(field string, f cronField) (uint64, error) {
+	max := f.max
+	if f.name == "day of week" {
+		max = 7
+	}
+
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("%s: bad step in %q", f.name, part)
+			}
+			rng, step = part[:i], n
+		}
+
+		lo, hi := f.min, max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			bounds := strings.SplitN(rng, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("%s: bad range %q", f.name, rng)
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("%s: bad value %q", f.name, rng)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < f.min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%s: %q out of range %d-%d", f.name, part, f.min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// Next returns the first activation strictly after t, in t's location.
+// the zero time is returned when the expression can never match, e.g. 30 February
+func (s Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	// step in wall clock time; Truncate works on absolute time and is off by
+	// the offset in zones like Asia/Kolkata that are not whole hours from UTC
+	t = advance(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, t.Location()), time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location()), time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, t.Location()), time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// advance returns next unless a repeated wall clock hour around a DST change
+// resolved it to a time not after t, in which case it steps d from t
+func advance(t, next time.Time, d time.Duration) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(d)
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
@@ -0,0 +1,55 @@
+package httplib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNextOffsetZones(t *testing.T) {
+	kolkata := time.FixedZone("IST", 5*3600+30*60)
+	kathmandu := time.FixedZone("NPT", 5*3600+45*60)
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"0 11 * * *", time.Date(2024, 3, 1, 9, 10, 0, 0, kolkata), time.Date(2024, 3, 1, 11, 0, 0, 0, kolkata)},
+		{"0 11 * * *", time.Date(2024, 3, 1, 11, 0, 0, 0, kolkata), time.Date(2024, 3, 2, 11, 0, 0, 0, kolkata)},
+		{"30 * * * *", time.Date(2024, 3, 1, 9, 40, 0, 0, kolkata), time.Date(2024, 3, 1, 10, 30, 0, 0, kolkata)},
+		{"0 11 * * *", time.Date(2024, 3, 1, 9, 10, 0, 0, kathmandu), time.Date(2024, 3, 1, 11, 0, 0, 0, kathmandu)},
+		{"5 9 * * *", time.Date(2024, 3, 1, 9, 4, 30, 0, kathmandu), time.Date(2024, 3, 1, 9, 5, 0, 0, kathmandu)},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%q): %v", tt.expr, err)
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q Next(%v) = %v, want %v", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleFullRangeDayFieldIsWildcard(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC) // a Friday
+	want := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	for _, expr := range []string{"0 0 15 * *", "0 0 15 * */1", "0 0 15 * 0-6", "0 0 15 * 0-7"} {
+		s, err := ParseSchedule(expr)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%q): %v", expr, err)
+		}
+		if got := s.Next(from); !got.Equal(want) {
+			t.Errorf("%q Next = %v, want %v", expr, got, want)
+		}
+	}
+
+	// both restricted: either day matches
+	s, err := ParseSchedule("0 0 15 * 5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.Next(from), time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package httplib
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/clairmont32/httplib/internal/logging"
+)
+
+// ToCurl renders req as an equivalent curl command for reproducing it by hand.
+// headers, URL and JSON or form body secrets are masked with the package
+// Redaction; the body is read through GetBody when possible and otherwise
+// replaced after reading
+func ToCurl(req *http.Request) (string, error) {
+	var b strings.Builder
+	b.WriteString("curl")
+	if req.Method != "" && req.Method != http.MethodGet {
+		b.WriteString(" -X " + req.Method)
+	}
+	b.WriteString(" " + shellQuote(RedactURL(req.URL)))
+
+	headers := RedactHeaders(req.Header)
+	if req.Host != "" && req.Host != req.URL.Host {
+		headers.Set("Host", req.Host)
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range headers[name] {
+			b.WriteString(" -H " + shellQuote(name+": "+v))
+		}
+	}
+
+	body, err := requestBody(req)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case len(body) == 0:
+	case utf8.Valid(body):
+		b.WriteString(" --data-binary " + shellQuote(redactBody(req.Header.Get("Content-Type"), body)))
+	default:
+		fmt.Fprintf(&b, " --data-binary @body.bin # %d byte binary body not shown", len(body))
+	}
+	return b.String(), nil
+}
+
+// requestBody returns the body of req leaving it readable for sending
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, err
+}
+
+// shellQuote wraps s in single quotes for POSIX shells
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// WithCurlLogging wraps next so every outgoing request is logged at debug
+// level as its ToCurl command. a nil next uses http.DefaultTransport
+func WithCurlLogging(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return curlLogging{next: next}
+}
+
+type curlLogging struct {
+	next http.RoundTripper
+}
+
+func (t curlLogging) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req
+	if req.GetBody == nil && req.Body != nil && req.Body != http.NoBody {
+		// reading the body in ToCurl replaces it, which the caller's request must not see
+		r = req.Clone(req.Context())
+	}
+	cmd, err := ToCurl(r)
+	if err != nil {
+		return nil, err
+	}
+	logging.Get().Debug("outgoing request", "method", r.Method, "curl", cmd)
+	return t.next.RoundTrip(r)
+}
@@ -0,0 +1,160 @@
+package httplib
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// DialerOption configures the dialer built by NewDialer and NewTransport
+type DialerOption func(*dialerConfig)
+
+// dialerConfig collects dialer settings and the socket options applied to each connection
+type dialerConfig struct {
+	dialer    net.Dialer
+	controls  []socketControl
+	afterDial []func(net.Conn) error
+	err       error
+}
+
+// Dialer is a net.Dialer that also applies options which must be set
+// once the connection is established, such as TCP_NODELAY
+type Dialer struct {
+	net.Dialer
+	afterDial []func(net.Conn) error
+}
+
+// DialContext connects to address and applies the post-connect options
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.Dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	for _, fn := range d.afterDial {
+		if err := fn(conn); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// Dial connects to address without a context
+func (d *Dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// socketControl sets an option on a raw socket before it connects
+type socketControl func(network string, fd uintptr) error
+
+// WithLocalAddr binds outbound connections to the source address ip
+func WithLocalAddr(ip net.IP) DialerOption {
+	return func(c *dialerConfig) {
+		c.dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+}
+
+// WithInterface forces outbound connections out of the named network interface.
+// on Linux this uses SO_BINDTODEVICE; elsewhere connections are bound
+// to the first address of the interface
+func WithInterface(name string) DialerOption {
+	return func(c *dialerConfig) {
+		bindInterface(c, name)
+	}
+}
+
+// NewDialer returns a Dialer configured by opts
+func NewDialer(opts ...DialerOption) (*Dialer, error) {
+	c := &dialerConfig{
+		dialer: net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	d := c.dialer
+	if len(c.controls) > 0 {
+		controls := c.controls
+		d.Control = func(network, address string, raw syscall.RawConn) error {
+			var ctlErr error
+			err := raw.Control(func(fd uintptr) {
+				for _, ctl := range controls {
+					if ctlErr = ctl(network, fd); ctlErr != nil {
+						return
+					}
+				}
+			})
+			if err != nil {
+				return err
+			}
+			return ctlErr
+		}
+	}
+	return &Dialer{Dialer: d, afterDial: c.afterDial}, nil
+}
+
+// NewTransport returns a copy of http.DefaultTransport dialing through NewDialer(opts...)
+// for use as NewClient.Transport
+func NewTransport(opts ...DialerOption) (*http.Transport, error) {
+	d, err := NewDialer(opts...)
+	if err != nil {
+		return nil, err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = d.DialContext
+	return t, nil
+}
+
+// WithDSCP marks outbound packets with the DiffServ code point dscp (0-63)
+// by setting IP_TOS on IPv4 and IPV6_TCLASS on IPv6 sockets
+func WithDSCP(dscp int) DialerOption {
+	return func(c *dialerConfig) {
+		if dscp < 0 || dscp > 63 {
+			c.err = fmt.Errorf("dscp %d out of range 0-63", dscp)
+			return
+		}
+		c.controls = append(c.controls, func(network string, fd uintptr) error {
+			return setTrafficClass(network, fd, dscp<<2)
+		})
+	}
+}
+
+// WithKeepAlive enables TCP keepalive probes after idle, repeated every interval
+// and giving up after count unanswered probes, so dead peers behind NAT devices
+// are detected on long-lived connections. interval and count are applied on
+// Linux only; other platforms probe every idle
+func WithKeepAlive(idle, interval time.Duration, count int) DialerOption {
+	return func(c *dialerConfig) {
+		keepAlive(c, idle, interval, count)
+	}
+}
+
+// WithUserTimeout sets TCP_USER_TIMEOUT so a connection with unacknowledged data
+// is dropped after d instead of the kernel default of many minutes. Linux only
+func WithUserTimeout(d time.Duration) DialerOption {
+	return func(c *dialerConfig) {
+		userTimeout(c, d)
+	}
+}
+
+// WithNoDelay controls Nagle's algorithm; Go disables it by default, pass false
+// to batch small writes on streaming connections
+func WithNoDelay(noDelay bool) DialerOption {
+	return func(c *dialerConfig) {
+		c.afterDial = append(c.afterDial, func(conn net.Conn) error {
+			if tc, ok := conn.(*net.TCPConn); ok {
+				return tc.SetNoDelay(noDelay)
+			}
+			return nil
+		})
+	}
+}
@@ -0,0 +1,53 @@
+//go:build linux
+// +build linux
+
+package httplib
+
+import (
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// bindInterface pins sockets to the interface with SO_BINDTODEVICE
+func bindInterface(c *dialerConfig, name string) {
+	c.controls = append(c.controls, func(_ string, fd uintptr) error {
+		return syscall.BindToDevice(int(fd), name)
+	})
+}
+
+// keepAlive sets the probe timings directly; Go's own keepalive handling is
+// disabled because it would overwrite the interval with the idle time after connect
+func keepAlive(c *dialerConfig, idle, interval time.Duration, count int) {
+	c.dialer.KeepAlive = -1
+	c.controls = append(c.controls, func(_ string, fd uintptr) error {
+		opts := []struct{ level, opt, value int }{
+			{syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, 1},
+			{syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE, seconds(idle)},
+			{syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, seconds(interval)},
+			{syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, count},
+		}
+		for _, o := range opts {
+			if o.value <= 0 {
+				continue
+			}
+			if err := syscall.SetsockoptInt(int(fd), o.level, o.opt, o.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// userTimeout sets TCP_USER_TIMEOUT in milliseconds
+func userTimeout(c *dialerConfig, d time.Duration) {
+	c.controls = append(c.controls, func(_ string, fd uintptr) error {
+		return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, unix.TCP_USER_TIMEOUT, int(d.Milliseconds()))
+	})
+}
+
+// seconds rounds d up to whole seconds as the socket options expect
+func seconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
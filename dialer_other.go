@@ -0,0 +1,42 @@
+//go:build !linux
+// +build !linux
+
+package httplib
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"time"
+)
+
+// bindInterface binds to the first address of the interface
+// since SO_BINDTODEVICE is Linux only
+func bindInterface(c *dialerConfig, name string) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		c.err = err
+		return
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		c.err = err
+		return
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok {
+			c.dialer.LocalAddr = &net.TCPAddr{IP: ipnet.IP}
+			return
+		}
+	}
+	c.err = fmt.Errorf("interface %s has no IP address", name)
+}
+
+// keepAlive falls back to Go's portable keepalive, which probes every idle
+func keepAlive(c *dialerConfig, idle, _ time.Duration, _ int) {
+	c.dialer.KeepAlive = idle
+}
+
+func userTimeout(c *dialerConfig, _ time.Duration) {
+	c.err = fmt.Errorf("TCP user timeout is not supported on %s", runtime.GOOS)
+}
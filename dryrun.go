@@ -0,0 +1,93 @@
+package httplib
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/clairmont32/httplib/internal/logging"
+)
+
+// DryRunHeader is set on synthetic dry run responses
+const DryRunHeader = "X-Httplib-Dry-Run"
+
+// DryRunRecord is a request a dry run would have sent
+type DryRunRecord struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// DryRun stands in for the network, e.g. to preview changes an automation
+// would make. set it on NewClient.DryRun: requests still go through live config,
+// profiles, events and transformers, then are validated, logged and recorded
+// here and answered with a synthetic response instead of being sent
+type DryRun struct {
+	// Status, Header and Body make up the synthetic response; zero Status is 200
+	Status int
+	Header http.Header
+	Body   []byte
+
+	// OnRequest is called with every recorded request
+	OnRequest func(DryRunRecord)
+
+	mu      sync.Mutex
+	records []DryRunRecord
+}
+
+// RoundTrip records req and returns the synthetic response
+func (d *DryRun) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" || req.URL.Host == "" {
+		return nil, fmt.Errorf("dry run: invalid url %q", req.URL)
+	}
+
+	rec := DryRunRecord{Method: req.Method, URL: req.URL.String(), Header: req.Header.Clone()}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		rec.Body = body
+	}
+	logging.Get().Info("dry run: request not sent", "method", rec.Method, "url", redactString(rec.URL), "bytes", len(rec.Body))
+
+	d.mu.Lock()
+	d.records = append(d.records, rec)
+	d.mu.Unlock()
+	if d.OnRequest != nil {
+		d.OnRequest(rec)
+	}
+
+	status := d.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	header := d.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set(DryRunHeader, "1")
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(d.Body)),
+		ContentLength: int64(len(d.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Records returns the requests recorded so far
+func (d *DryRun) Records() []DryRunRecord {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]DryRunRecord(nil), d.records...)
+}
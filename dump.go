@@ -0,0 +1,138 @@
+package httplib
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/clairmont32/httplib/internal/logging"
+)
+
+// defaultDumpBody caps the body bytes of each dump when DumpConfig.MaxBody is zero
+const defaultDumpBody = 4 << 10
+
+// Dump is the wire form of one request and its response. headers, URL and
+// JSON or form body secrets are masked with the package Redaction
+type Dump struct {
+	Request  []byte
+	Response []byte // nil when the request failed
+	Err      error
+}
+
+// DumpConfig controls WithDebugDump
+type DumpConfig struct {
+	// MaxBody caps how many body bytes each dump includes; zero uses 4KB and
+	// a negative value leaves bodies out
+	MaxBody int
+	// OnDump receives every dump; nil logs it at debug level
+	OnDump func(Dump)
+}
+
+// WithDebugDump returns a transport that captures the request and response of
+// every round trip through next, with bodies up to cfg.MaxBody, for
+// troubleshooting failed upstream calls. the captured part of the response body
+// is read before the response is returned; readers still get the whole body.
+// a nil next uses http.DefaultTransport
+func WithDebugDump(next http.RoundTripper, cfg DumpConfig) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if cfg.MaxBody == 0 {
+		cfg.MaxBody = defaultDumpBody
+	}
+	return dumpTransport{next: next, cfg: cfg}
+}
+
+type dumpTransport struct {
+	next http.RoundTripper
+	cfg  DumpConfig
+}
+
+// RoundTrip dumps req, sends it and dumps the response once its head arrived
+func (t dumpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var d Dump
+	r := req
+	if req.Body != nil && req.Body != http.NoBody && t.cfg.MaxBody > 0 {
+		r = req.Clone(req.Context())
+		var prefix []byte
+		prefix, r.Body = peekPrefix(req.Body, req.Header.Get("Content-Type"), t.cfg.MaxBody)
+		d.Request = dumpRequest(req, prefix)
+	} else {
+		d.Request = dumpRequest(req, nil)
+	}
+
+	resp, err := t.next.RoundTrip(r)
+	if err != nil {
+		d.Err = redactErr(err)
+		t.emit(d)
+		return nil, err
+	}
+	var prefix []byte
+	if t.cfg.MaxBody > 0 && resp.Body != nil {
+		prefix, resp.Body = peekPrefix(resp.Body, resp.Header.Get("Content-Type"), t.cfg.MaxBody)
+	}
+	d.Response = dumpResponse(resp, prefix)
+	t.emit(d)
+	return resp, nil
+}
+
+func (t dumpTransport) emit(d Dump) {
+	if t.cfg.OnDump != nil {
+		t.cfg.OnDump(d)
+		return
+	}
+	kv := []interface{}{"request", string(d.Request)}
+	if d.Response != nil {
+		kv = append(kv, "response", string(d.Response))
+	}
+	if d.Err != nil {
+		kv = append(kv, "error", d.Err)
+	}
+	logging.Get().Debug("http dump", kv...)
+}
+
+// peekPrefix reads up to n bytes of body for the dump, one more to tell whether
+// it was cut, and returns the redacted prefix and a body that still yields everything
+func peekPrefix(body io.ReadCloser, contentType string, n int) ([]byte, io.ReadCloser) {
+	read, _ := io.ReadAll(io.LimitReader(body, int64(n)+1))
+	rest := struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(read), body), body}
+	if len(read) > n {
+		return append([]byte(redactBody(contentType, read[:n])), "\n[truncated]"...), rest
+	}
+	return []byte(redactBody(contentType, read)), rest
+}
+
+// dumpRequest renders the head of req with redacted headers and URL, then prefix
+func dumpRequest(req *http.Request, prefix []byte) []byte {
+	r := req.Clone(req.Context())
+	r.Header = RedactHeaders(req.Header)
+	if u, err := url.Parse(RedactURL(req.URL)); err == nil {
+		r.URL = u
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		// DumpRequestOut stands in a dummy body of ContentLength for the real one
+		r.Body = io.NopCloser(bytes.NewReader(nil))
+	}
+	head, err := httputil.DumpRequestOut(r, false)
+	if err != nil {
+		return []byte(req.Method + " " + RedactURL(req.URL) + "\r\n\r\n")
+	}
+	return append(head, prefix...)
+}
+
+// dumpResponse renders the head of resp with redacted headers, then prefix
+func dumpResponse(resp *http.Response, prefix []byte) []byte {
+	c := *resp
+	c.Header = RedactHeaders(resp.Header)
+	c.Body = nil
+	head, err := httputil.DumpResponse(&c, false)
+	if err != nil {
+		return []byte(resp.Status + "\r\n\r\n")
+	}
+	return append(head, prefix...)
+}
@@ -0,0 +1,232 @@
+package httplib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Extract returns the value at path in a JSON response body without defining
+// a struct for it. path uses dotted keys, [n] indexes and [*] projections,
+// e.g. "data.items[*].id". a projection yields a list of the remaining path
+// applied to each element; elements missing it are skipped and nested
+// projections are flattened. numbers are returned as json.Number.
+// the body is restored afterwards so it can be extracted from again
+func Extract(resp *http.Response, path string) (interface{}, error) {
+	body, err := peekBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("extract %q: body is not valid JSON: %w", path, err)
+	}
+	v, err := LookupJSON(doc, path)
+	if err != nil {
+		return nil, fmt.Errorf("extract %q: %w", path, err)
+	}
+	return v, nil
+}
+
+// ExtractString returns the string at path
+func ExtractString(resp *http.Response, path string) (string, error) {
+	v, err := Extract(resp, path)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("extract %q: value is %s, not a string", path, jsonKind(v))
+	}
+	return s, nil
+}
+
+// ExtractInt returns the integer at path; numbers with a fraction are an error
+func ExtractInt(resp *http.Response, path string) (int64, error) {
+	v, err := Extract(resp, path)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("extract %q: value is %s, not a number", path, jsonKind(v))
+	}
+	if i, err := n.Int64(); err == nil {
+		return i, nil
+	}
+	f, err := n.Float64()
+	if err != nil || f != math.Trunc(f) || f > math.MaxInt64 || f < math.MinInt64 {
+		return 0, fmt.Errorf("extract %q: %s is not an integer", path, n)
+	}
+	return int64(f), nil
+}
+
+// ExtractStringSlice returns the strings in the array at path, typically a projection
+func ExtractStringSlice(resp *http.Response, path string) ([]string, error) {
+	v, err := Extract(resp, path)
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("extract %q: value is %s, not an array", path, jsonKind(v))
+	}
+	out := make([]string, 0, len(arr))
+	for i, e := range arr {
+		s, ok := e.(string)
+		if !ok {
+			return nil, fmt.Errorf("extract %q: element %d is %s, not a string", path, i, jsonKind(e))
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// LookupJSON walks a decoded JSON document following path, as described on Extract,
+// and reports where it stopped on failure
+func LookupJSON(doc interface{}, path string) (interface{}, error) {
+	segs, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return lookupSegments(doc, segs, "$")
+}
+
+// pathSegment is a single step of a path: an object key, an array index or a [*] projection
+type pathSegment struct {
+	key   string
+	index int
+	isIdx bool
+	all   bool
+}
+
+// parseJSONPath splits "a.b[0].c[*]" into its segments
+func parseJSONPath(path string) ([]pathSegment, error) {
+	var segs []pathSegment
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		var idxs []pathSegment
+		if open := strings.IndexByte(part, '['); open >= 0 {
+			key = part[:open]
+			rest := part[open:]
+			for rest != "" {
+				end := strings.IndexByte(rest, ']')
+				if rest[0] != '[' || end < 0 {
+					return nil, fmt.Errorf("malformed index in %q", part)
+				}
+				if rest[1:end] == "*" {
+					idxs = append(idxs, pathSegment{all: true})
+				} else {
+					n, err := strconv.Atoi(rest[1:end])
+					if err != nil {
+						return nil, fmt.Errorf("malformed index in %q", part)
+					}
+					idxs = append(idxs, pathSegment{index: n, isIdx: true})
+				}
+				rest = rest[end+1:]
+			}
+		}
+		if key != "" {
+			segs = append(segs, pathSegment{key: key})
+		}
+		segs = append(segs, idxs...)
+	}
+	return segs, nil
+}
+
+func lookupSegments(cur interface{}, segs []pathSegment, walked string) (interface{}, error) {
+	for i, seg := range segs {
+		switch {
+		case seg.all:
+			arr, ok := cur.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s is %s, not an array", walked, jsonKind(cur))
+			}
+			out := make([]interface{}, 0, len(arr))
+			rest := segs[i+1:]
+			nested := hasProjection(rest)
+			for n, elem := range arr {
+				v, err := lookupSegments(elem, rest, walked+"["+strconv.Itoa(n)+"]")
+				if err != nil {
+					continue
+				}
+				if sub, ok := v.([]interface{}); ok && nested {
+					out = append(out, sub...)
+					continue
+				}
+				out = append(out, v)
+			}
+			return out, nil
+
+		case seg.isIdx:
+			arr, ok := cur.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s is %s, not an array", walked, jsonKind(cur))
+			}
+			if seg.index < 0 || seg.index >= len(arr) {
+				return nil, fmt.Errorf("index %d out of range at %s (len %d)", seg.index, walked, len(arr))
+			}
+			cur = arr[seg.index]
+			walked += "[" + strconv.Itoa(seg.index) + "]"
+
+		default:
+			obj, ok := cur.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s is %s, not an object", walked, jsonKind(cur))
+			}
+			next, ok := obj[seg.key]
+			if !ok {
+				return nil, fmt.Errorf("key %q not found at %s", seg.key, walked)
+			}
+			cur = next
+			walked += "." + seg.key
+		}
+	}
+	return cur, nil
+}
+
+func hasProjection(segs []pathSegment) bool {
+	for _, s := range segs {
+		if s.all {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case float64, json.Number:
+		return "a number"
+	case bool:
+		return "a bool"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// peekBody reads the body and replaces it so it can be read again
+func peekBody(resp *http.Response) ([]byte, error) {
+	if resp == nil || resp.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return body, err
+}
@@ -0,0 +1,46 @@
+package httplib
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/clairmont32/httplib/retry"
+)
+
+// DefaultUserAgent is sent by Fetch and FetchJSON
+const DefaultUserAgent = "httplib (+https://github.com/clairmont32/httplib)"
+
+// Fetch GETs url with the package defaults and returns the body
+// for scripts that need a single call without setting up a FormRequest.
+// transient failures are retried with retry.Default unless the default client sets its own policy
+func Fetch(url string) ([]byte, error) {
+	return DefaultRequestWithContext(fetchContext(), fetchRequest(url), []Headers{
+		{Key: "User-Agent", Value: DefaultUserAgent},
+	})
+}
+
+// FetchJSON GETs url with the package defaults and decodes the JSON body into v
+func FetchJSON(url string, v interface{}) error {
+	body, err := DefaultRequestWithContext(fetchContext(), fetchRequest(url), []Headers{
+		{Key: "User-Agent", Value: DefaultUserAgent},
+		{Key: "Accept", Value: "application/json"},
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// fetchContext applies the default retry policy unless the default client has one
+func fetchContext() context.Context {
+	ctx := context.Background()
+	if sharedClient().Retry == nil {
+		ctx = WithRetryPolicy(ctx, retry.Default())
+	}
+	return ctx
+}
+
+func fetchRequest(url string) *FormRequest {
+	return &FormRequest{BaseURL: url, Method: http.MethodGet}
+}
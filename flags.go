@@ -0,0 +1,65 @@
+package httplib
+
+import (
+	"context"
+	"sync"
+)
+
+// FlagProvider reports whether a named behavior is enabled, so risky client
+// behaviors can be rolled out gradually from a feature-flag service.
+// adapters for LaunchDarkly or OpenFeature clients only need this one method
+type FlagProvider interface {
+	// BoolFlag returns the value of flag for ctx, or def when the flag is unknown
+	BoolFlag(ctx context.Context, flag string, def bool) bool
+}
+
+// Flag names consulted through NewClient.Flags
+const (
+	// FlagRetries gates automatic retries
+	FlagRetries = "httplib.retries"
+)
+
+// StaticFlags is a FlagProvider backed by a fixed map, for tests and simple deployments
+type StaticFlags map[string]bool
+
+// BoolFlag returns the value stored for flag, or def
+func (s StaticFlags) BoolFlag(_ context.Context, flag string, def bool) bool {
+	if v, ok := s[flag]; ok {
+		return v
+	}
+	return def
+}
+
+// MutableFlags is a FlagProvider whose values can be changed at runtime; the zero value is ready to use
+type MutableFlags struct {
+	mu    sync.RWMutex
+	flags map[string]bool
+}
+
+// Set enables or disables flag
+func (m *MutableFlags) Set(flag string, enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.flags == nil {
+		m.flags = make(map[string]bool)
+	}
+	m.flags[flag] = enabled
+}
+
+// BoolFlag returns the current value of flag, or def
+func (m *MutableFlags) BoolFlag(_ context.Context, flag string, def bool) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if v, ok := m.flags[flag]; ok {
+		return v
+	}
+	return def
+}
+
+// flagEnabled asks the client's provider about flag; behaviors are on when no provider is set
+func (c NewClient) flagEnabled(ctx context.Context, flag string) bool {
+	if c.Flags == nil {
+		return true
+	}
+	return c.Flags.BoolFlag(ctx, flag, true)
+}
@@ -0,0 +1,151 @@
+package httplib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// Flatten turns a decoded JSON document into a map from Extract style paths
+// to leaf values, e.g. {"a":{"b":[1]}} becomes {"a.b[0]": 1}. empty objects and
+// arrays are kept as leaves so Unflatten restores them. object keys containing
+// '.' or '[' cannot be told apart from nesting and do not round trip
+func Flatten(doc interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	flattenInto(out, "", doc)
+	return out
+}
+
+// FlattenResponse decodes a JSON response body and flattens it.
+// numbers are kept as json.Number and the body is restored afterwards
+func FlattenResponse(resp *http.Response) (map[string]interface{}, error) {
+	body, err := peekBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("flatten: body is not valid JSON: %w", err)
+	}
+	return Flatten(doc), nil
+}
+
+func flattenInto(out map[string]interface{}, prefix string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			out[prefix] = map[string]interface{}{}
+			return
+		}
+		for k, child := range v {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flattenInto(out, key, child)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			out[prefix] = []interface{}{}
+			return
+		}
+		for i, child := range v {
+			flattenInto(out, prefix+"["+strconv.Itoa(i)+"]", child)
+		}
+	default:
+		out[prefix] = v
+	}
+}
+
+// Unflatten rebuilds the document Flatten produced. array positions missing
+// from flat are filled with null
+func Unflatten(flat map[string]interface{}) (interface{}, error) {
+	// sorted keys make conflicting paths fail the same way every time
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var doc interface{}
+	for _, k := range keys {
+		segs, err := parseJSONPath(k)
+		if err != nil {
+			return nil, fmt.Errorf("unflatten %q: %w", k, err)
+		}
+		if doc, err = setPath(doc, segs, flat[k]); err != nil {
+			return nil, fmt.Errorf("unflatten %q: %w", k, err)
+		}
+	}
+	return doc, nil
+}
+
+// setPath stores value at segs below node, creating objects and arrays as needed
+func setPath(node interface{}, segs []pathSegment, value interface{}) (interface{}, error) {
+	if len(segs) == 0 {
+		if node != nil && !isEmptyContainer(value) {
+			return nil, fmt.Errorf("conflicting values")
+		}
+		if node != nil {
+			return node, nil
+		}
+		return value, nil
+	}
+
+	seg := segs[0]
+	switch {
+	case seg.all:
+		return nil, fmt.Errorf("projections cannot be set")
+
+	case seg.isIdx:
+		if node == nil || isEmptyContainer(node) {
+			node = []interface{}{}
+		}
+		arr, ok := node.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s used as an array", jsonKind(node))
+		}
+		if seg.index < 0 {
+			return nil, fmt.Errorf("negative index %d", seg.index)
+		}
+		for len(arr) <= seg.index {
+			arr = append(arr, nil)
+		}
+		child, err := setPath(arr[seg.index], segs[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		arr[seg.index] = child
+		return arr, nil
+
+	default:
+		if node == nil || isEmptyContainer(node) {
+			node = map[string]interface{}{}
+		}
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s used as an object", jsonKind(node))
+		}
+		child, err := setPath(obj[seg.key], segs[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		obj[seg.key] = child
+		return obj, nil
+	}
+}
+
+func isEmptyContainer(v interface{}) bool {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package httplib
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentTypeForm is the media type of form-urlencoded bodies
+const ContentTypeForm = "application/x-www-form-urlencoded"
+
+// EncodeForm converts v to form values for bodies and FormRequest.Query.
+// v may be url.Values, a map with string
+// keys, or a struct whose fields are named by their form tag:
+//
+//	type login struct {
+//		User     string   `form:"username"`
+//		Password string   `form:"password"`
+//		Scope    []string `form:"scope,omitempty"`
+//		Internal string   `form:"-"`
+//	}
+//
+// untagged exported fields use their name, embedded structs are flattened,
+// slices repeat the key and time.Time is encoded as RFC 3339
+func EncodeForm(v interface{}) (url.Values, error) {
+	switch v := v.(type) {
+	case url.Values:
+		return v, nil
+	case map[string]string:
+		form := make(url.Values, len(v))
+		for k, s := range v {
+			form.Set(k, s)
+		}
+		return form, nil
+	case map[string][]string:
+		return url.Values(v), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return url.Values{}, nil
+		}
+		rv = rv.Elem()
+	}
+	form := url.Values{}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("form: map keys must be strings, not %s", rv.Type().Key())
+		}
+		iter := rv.MapRange()
+		for iter.Next() {
+			if err := addFormValue(form, iter.Key().String(), iter.Value(), false); err != nil {
+				return nil, err
+			}
+		}
+	case reflect.Struct:
+		if err := addFormStruct(form, rv); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("form: cannot encode %T", v)
+	}
+	return form, nil
+}
+
+func addFormStruct(form url.Values, rv reflect.Value) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("form")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := rv.Field(i)
+		if f.Anonymous && name == "" {
+			for fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					break
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if err := addFormStruct(form, fv); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if err := addFormValue(form, name, fv, opts == "omitempty"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addFormValue adds v under key, one value per element for slices
+func addFormValue(form url.Values, key string, v reflect.Value, omitEmpty bool) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if omitEmpty && v.IsZero() {
+		return nil
+	}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < v.Len(); i++ {
+			if err := addFormValue(form, key, v.Index(i), false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	s, err := formString(v)
+	if err != nil {
+		return fmt.Errorf("form field %s: %w", key, err)
+	}
+	form.Add(key, s)
+	return nil
+}
+
+func formString(v reflect.Value) (string, error) {
+	switch x := v.Interface().(type) {
+	case time.Time:
+		return x.Format(time.RFC3339), nil
+	case encoding.TextMarshaler:
+		b, err := x.MarshalText()
+		return string(b), err
+	case fmt.Stringer:
+		return x.String(), nil
+	case []byte:
+		return string(x), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	default:
+		return "", fmt.Errorf("unsupported type %s", v.Type())
+	}
+}
+
+// addQuery appends the parameters of query, encoded with EncodeForm, to rawURL
+func addQuery(rawURL string, query interface{}) (string, error) {
+	values, err := EncodeForm(query)
+	if err != nil {
+		return "", err
+	}
+	if len(values) == 0 {
+		return rawURL, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.RawQuery != "" {
+		u.RawQuery += "&"
+	}
+	u.RawQuery += values.Encode()
+	return u.String(), nil
+}
@@ -1,7 +1,10 @@
 module github.com/clairmont32/httplib
 
-go 1.17
+go 1.18
 
-require github.com/sirupsen/logrus v1.8.1
-
-require golang.org/x/sys v0.1.0 // indirect
+require (
+	github.com/sirupsen/logrus v1.8.1
+	golang.org/x/sync v0.1.0
+	golang.org/x/sys v0.1.0
+	golang.org/x/text v0.4.0
+)
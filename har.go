@@ -0,0 +1,308 @@
+package httplib
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// HARRecorder captures all traffic through Next for export in HAR 1.2 format,
+// which browsers and HAR viewers open, e.g. to attach to bug reports. headers,
+// URL and JSON or form body secrets are masked with the package Redaction. response bodies are
+// buffered to record them. a nil Next uses http.DefaultTransport
+type HARRecorder struct {
+	Next http.RoundTripper
+	// MaxBody caps the recorded bytes of each body; zero records them whole
+	MaxBody int
+
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+type harLog struct {
+	Log struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// harTimings are in milliseconds; -1 marks phases that did not happen
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// RoundTrip performs req and records it with its response or error
+func (h *HARRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := h.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if req.GetBody == nil && req.Body != nil && req.Body != http.NoBody {
+		// reading the body replaces it, which the caller's request must not see
+		req = req.Clone(req.Context())
+	}
+	reqBody, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	traced, timings := withTrace(req)
+	resp, err := next.RoundTrip(traced)
+	if err != nil {
+		h.add(h.entry(req, reqBody, nil, nil, start, timings.snapshot(), err))
+		return nil, err
+	}
+	headersAt := time.Now()
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	e := h.entry(req, reqBody, resp, body, start, timings.snapshot(), nil)
+	e.Timings.Receive = ms(time.Since(headersAt))
+	e.Time = ms(time.Since(start))
+	h.add(e)
+	return resp, nil
+}
+
+func (h *HARRecorder) add(e harEntry) {
+	h.mu.Lock()
+	h.entries = append(h.entries, e)
+	h.mu.Unlock()
+}
+
+func (h *HARRecorder) entry(req *http.Request, reqBody []byte, resp *http.Response, body []byte, start time.Time, t tracePhases, err error) harEntry {
+	e := harEntry{
+		StartedDateTime: start.UTC().Format(time.RFC3339Nano),
+		Time:            ms(time.Since(start)),
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         RedactURL(req.URL),
+			HTTPVersion: req.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(RedactHeaders(req.Header)),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    int64(len(reqBody)),
+		},
+		Timings: harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+	}
+	if e.Request.HTTPVersion == "" {
+		e.Request.HTTPVersion = "HTTP/1.1"
+	}
+	if u, perr := parseRedactedQuery(req); perr == nil {
+		e.Request.QueryString = u
+	}
+	if len(reqBody) > 0 {
+		text, _ := harText(h.redacted(req.Header.Get("Content-Type"), reqBody))
+		e.Request.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: text}
+	}
+
+	if t.DNS > 0 {
+		e.Timings.DNS = ms(t.DNS)
+	}
+	if t.Connect > 0 {
+		e.Timings.Connect = ms(t.Connect)
+	}
+	if t.TLS > 0 {
+		e.Timings.SSL = ms(t.TLS)
+	}
+	if !t.gotConn.IsZero() {
+		e.Timings.Blocked = ms(t.offset(t.gotConn)) - nonNegative(e.Timings.DNS) - nonNegative(e.Timings.Connect) - nonNegative(e.Timings.SSL)
+		if e.Timings.Blocked < 0 {
+			e.Timings.Blocked = 0
+		}
+	}
+	if !t.wroteRequest.IsZero() && !t.gotConn.IsZero() {
+		e.Timings.Send = ms(t.wroteRequest.Sub(t.gotConn))
+	}
+	if t.FirstByte > 0 && !t.wroteRequest.IsZero() {
+		e.Timings.Wait = ms(t.FirstByte - t.offset(t.wroteRequest))
+	}
+
+	if err != nil {
+		// HAR has no field for transport errors; status 0 is what browsers record
+		e.Response = harResponse{Cookies: []harNameValue{}, Headers: []harNameValue{}, HeadersSize: -1, BodySize: -1}
+		e.Comment = redactErr(err).Error()
+		return e
+	}
+	text, encoding := harText(h.redacted(resp.Header.Get("Content-Type"), body))
+	e.Response = harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(RedactHeaders(resp.Header)),
+		Content:     harContent{Size: int64(len(body)), MimeType: resp.Header.Get("Content-Type"), Text: text, Encoding: encoding},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    int64(len(body)),
+	}
+	return e
+}
+
+// redacted is the capped body with its JSON or form secrets masked
+func (h *HARRecorder) redacted(contentType string, b []byte) []byte {
+	return []byte(redactBody(contentType, h.capped(b)))
+}
+
+func (h *HARRecorder) capped(b []byte) []byte {
+	if h.MaxBody > 0 && len(b) > h.MaxBody {
+		return b[:h.MaxBody]
+	}
+	return b
+}
+
+// parseRedactedQuery lists the query parameters of req with secrets masked
+func parseRedactedQuery(req *http.Request) ([]harNameValue, error) {
+	u, err := req.URL.Parse(RedactURL(req.URL))
+	if err != nil {
+		return nil, err
+	}
+	out := []harNameValue{}
+	for name, vs := range u.Query() {
+		for _, v := range vs {
+			out = append(out, harNameValue{Name: name, Value: v})
+		}
+	}
+	return out, nil
+}
+
+func harHeaders(h http.Header) []harNameValue {
+	out := []harNameValue{}
+	for name, vs := range h {
+		for _, v := range vs {
+			out = append(out, harNameValue{Name: name, Value: v})
+		}
+	}
+	return out
+}
+
+// harText returns b as text, or base64 with its encoding when it is binary
+func harText(b []byte) (string, string) {
+	if utf8.Valid(b) {
+		return string(b), ""
+	}
+	return base64.StdEncoding.EncodeToString(b), "base64"
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func nonNegative(f float64) float64 {
+	if f < 0 {
+		return 0
+	}
+	return f
+}
+
+// WriteTo writes everything recorded so far as a HAR 1.2 document
+func (h *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	var doc harLog
+	doc.Log.Version = "1.2"
+	doc.Log.Creator = harCreator{Name: "httplib", Version: "1"}
+	h.mu.Lock()
+	doc.Log.Entries = append([]harEntry{}, h.entries...)
+	h.mu.Unlock()
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return 0, err
+	}
+	return buf.WriteTo(w)
+}
+
+// WriteFile writes the recording to path, e.g. "trace.har"
+func (h *HARRecorder) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := h.WriteTo(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Reset discards the recorded entries
+func (h *HARRecorder) Reset() {
+	h.mu.Lock()
+	h.entries = nil
+	h.mu.Unlock()
+}
@@ -0,0 +1,36 @@
+package httplib
+
+import (
+	"context"
+	"net/http"
+)
+
+// HeaderFunc computes a default header value for a request from its context,
+// e.g. the caller of an auth context for X-On-Behalf-Of or the user's locale
+// for Accept-Language. returning "" leaves the header unset
+type HeaderFunc func(ctx context.Context) string
+
+// applyHeaderFuncs returns req with the computed headers it does not already
+// carry, leaving the caller's request untouched. a panicking HeaderFunc fails
+// the request with a *PanicError
+func applyHeaderFuncs(req *http.Request, funcs map[string]HeaderFunc) (_ *http.Request, err error) {
+	defer recoverInto(&err)
+	var r *http.Request
+	for name, fn := range funcs {
+		if req.Header.Get(name) != "" {
+			continue
+		}
+		v := fn(req.Context())
+		if v == "" {
+			continue
+		}
+		if r == nil {
+			r = req.Clone(req.Context())
+		}
+		r.Header.Set(name, v)
+	}
+	if r == nil {
+		return req, nil
+	}
+	return r, nil
+}
@@ -0,0 +1,84 @@
+package httplib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Heartbeat POSTs Payload to URL every Interval to report liveness to a monitor
+// and calls OnFailure when Threshold posts in a row have failed
+type Heartbeat struct {
+	URL     string
+	Payload []byte
+	Headers []Headers
+
+	Interval time.Duration
+	// Threshold is the number of consecutive failures that counts as an outage; values below 1 mean 1
+	Threshold int
+
+	// OnFailure is called once per outage, when the failure count reaches Threshold
+	OnFailure func(failures int, err error)
+	// OnRecover is called when a post succeeds after OnFailure fired; optional
+	OnRecover func()
+
+	// Client performs the requests; nil uses the default client
+	Client *NewClient
+}
+
+// Run posts a heartbeat immediately and then every Interval until ctx is done
+func (h *Heartbeat) Run(ctx context.Context) error {
+	if h.Interval <= 0 {
+		return errors.New("Heartbeat requires a positive Interval")
+	}
+	threshold := h.Threshold
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	t := time.NewTicker(h.Interval)
+	defer t.Stop()
+
+	failures := 0
+	for {
+		err := h.beat(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		switch {
+		case err == nil:
+			if failures >= threshold && h.OnRecover != nil {
+				h.OnRecover()
+			}
+			failures = 0
+		default:
+			failures++
+			if failures == threshold && h.OnFailure != nil {
+				h.OnFailure(failures, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (h *Heartbeat) beat(ctx context.Context) error {
+	req, err := (&FormRequest{BaseURL: h.URL, Payload: h.Payload, Method: http.MethodPost}).FormRequestWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, hdr := range h.Headers {
+		hdr.AddHeader(req)
+	}
+	resp, err := doWith(h.Client, req)
+	if err != nil {
+		return err
+	}
+	_, err = ProcessStatusCode(resp)
+	return err
+}
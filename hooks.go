@@ -0,0 +1,56 @@
+package httplib
+
+import (
+	"net/http"
+	"time"
+)
+
+// Hooks are callbacks run around every DoRequest call, once per call rather
+// than per attempt. panics in them fail the request with a *PanicError, except
+// in OnError, whose panics are logged so the request error is kept
+type Hooks struct {
+	// OnBeforeRequest may modify a copy of the request after profiles and live
+	// config were applied; an error aborts the request without sending it
+	OnBeforeRequest func(req *http.Request) error
+
+	// OnAfterResponse sees every response DoRequest returns and how long the
+	// call took. it must leave the body unread or replace it
+	OnAfterResponse func(resp *http.Response, elapsed time.Duration)
+
+	// OnError sees every error DoRequest returns with the request passed to it
+	OnError func(req *http.Request, err error)
+}
+
+// before returns a copy of req modified by OnBeforeRequest
+func (h Hooks) before(req *http.Request) (r *http.Request, err error) {
+	if h.OnBeforeRequest == nil {
+		return req, nil
+	}
+	defer recoverInto(&err)
+	r = req.Clone(req.Context())
+	return r, h.OnBeforeRequest(r)
+}
+
+// after runs OnAfterResponse or OnError for the outcome of req. a panic in
+// OnError is logged to log and the request error returned unchanged
+func (h Hooks) after(req *http.Request, resp *http.Response, elapsed time.Duration, err error, log Logger) (rerr error) {
+	rerr = err
+	if err != nil {
+		if h.OnError != nil {
+			var herr error
+			func() {
+				defer recoverInto(&herr)
+				h.OnError(req, err)
+			}()
+			if herr != nil {
+				log.Error("OnError hook failed", "method", req.Method, "url", RedactURL(req.URL), "error", herr, "request_error", redactErr(err))
+			}
+		}
+		return err
+	}
+	if h.OnAfterResponse != nil {
+		defer recoverInto(&rerr)
+		h.OnAfterResponse(resp, elapsed)
+	}
+	return rerr
+}
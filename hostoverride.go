@@ -0,0 +1,74 @@
+package httplib
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"sync"
+)
+
+// WithHost sets the Host header sent with req independently of the URL that is dialed,
+// e.g. to reach a vhost through a load balancer IP. req is modified and returned
+func WithHost(req *http.Request, host string) *http.Request {
+	req.Host = host
+	return req
+}
+
+// WithServerName returns a copy of req whose TLS handshake sends name as SNI
+// and verifies the certificate against it instead of the URL host.
+// it only takes effect on clients whose Transport is a ServerNameTransport
+func WithServerName(req *http.Request, name string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), serverNameKey, name))
+}
+
+// ServerNameTransport routes requests carrying a WithServerName override through
+// a dedicated copy of base so connections made for one server name are never
+// reused for another. a nil base clones http.DefaultTransport
+func ServerNameTransport(base *http.Transport) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	return &serverNameTransport{base: base, byName: make(map[string]*http.Transport)}
+}
+
+type serverNameTransport struct {
+	base *http.Transport
+
+	mu     sync.Mutex
+	byName map[string]*http.Transport
+}
+
+// RoundTrip sends req through the transport for its server name
+func (s *serverNameTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name, _ := req.Context().Value(serverNameKey).(string)
+	if name == "" || req.URL.Scheme != "https" {
+		return s.base.RoundTrip(req)
+	}
+	return s.transportFor(name).RoundTrip(req)
+}
+
+// CloseIdleConnections closes idle connections of every underlying transport
+func (s *serverNameTransport) CloseIdleConnections() {
+	s.base.CloseIdleConnections()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.byName {
+		t.CloseIdleConnections()
+	}
+}
+
+func (s *serverNameTransport) transportFor(name string) *http.Transport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.byName[name]; ok {
+		return t
+	}
+
+	t := s.base.Clone()
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	t.TLSClientConfig.ServerName = name
+	s.byName[name] = t
+	return t
+}
@@ -5,53 +5,170 @@ package httplib
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
-	"strings"
+	"sync"
 	"time"
 
-	log "github.com/sirupsen/logrus"
+	"github.com/clairmont32/httplib/internal/logging"
+	"github.com/clairmont32/httplib/observe"
+	"github.com/clairmont32/httplib/ratelimit"
+	"github.com/clairmont32/httplib/retry"
 )
 
 // FormRequest contains basic fields needed for a HTTP request
 // it has a method of FormRequest which returns a *http.Request
 type FormRequest struct {
+	// BaseURL and Endpoint are joined with JoinURL, so slashes between them
+	// may be doubled or missing. without a BaseURL, Endpoint is a full URL
 	BaseURL  string
 	Endpoint string
 	Payload  []byte
 	Method   string
+
+	// PathParams fill the {name} placeholders of Endpoint, escaped, when set;
+	// see ExpandPath
+	PathParams map[string]string
+
+	// Template renders the body from Data in place of Payload when set
+	Template *PayloadTemplate
+	Data     interface{}
+
+	// Body is marshaled to JSON and sent with a JSON Content-Type when
+	// Payload and Template are not set
+	Body interface{}
+
+	// Form is encoded with EncodeForm and sent as a form-urlencoded body when
+	// Payload, Template and Body are not set
+	Form interface{}
+
+	// Query is encoded with EncodeForm and added to the query of the URL,
+	// after any parameters already in Endpoint
+	Query interface{}
 }
 
+// defaultClient is shared by DefaultClient and everything built on it.
+// it is created on first use and guarded for concurrent access
+var (
+	defaultClientMu sync.RWMutex
+	defaultClient   *NewClient
+)
+
 // DefaultClient provides a default client with 10s timeout
+// unless replaced with SetDefaultClient
 func DefaultClient(req *http.Request) (*http.Response, http.Header, error) {
-	c := NewClient{
-		Transport:     nil,
-		CheckRedirect: nil,
-		Jar:           nil,
-		Timeout:       10 * time.Second,
+	return sharedClient().DoRequest(req)
+}
+
+// SetDefaultClient replaces the client used by DefaultClient, DefaultRequest
+// and the helpers built on them, e.g. to point tests at a mock transport.
+// nil restores the built-in client. the previous client is returned so it can be restored
+func SetDefaultClient(c *NewClient) (previous *NewClient) {
+	defaultClientMu.Lock()
+	defer defaultClientMu.Unlock()
+	previous = defaultClient
+	defaultClient = c
+	return previous
+}
+
+// sharedClient returns the default client, creating it on first use
+func sharedClient() *NewClient {
+	defaultClientMu.RLock()
+	c := defaultClient
+	defaultClientMu.RUnlock()
+	if c != nil {
+		return c
 	}
-	return c.DoRequest(req)
+
+	defaultClientMu.Lock()
+	defer defaultClientMu.Unlock()
+	if defaultClient == nil {
+		defaultClient = &NewClient{
+			Transport:     nil,
+			CheckRedirect: nil,
+			Jar:           nil,
+			Timeout:       10 * time.Second,
+		}
+	}
+	return defaultClient
 }
 
 // FormRequest creates a new HTTP request
 func (r FormRequest) FormRequest() (*http.Request, error) {
+	return r.FormRequestWithContext(context.Background())
+}
+
+// FormRequestWithContext creates a new HTTP request bound to ctx, so cancelling
+// ctx or reaching its deadline aborts the call
+func (r FormRequest) FormRequestWithContext(ctx context.Context) (*http.Request, error) {
 	var (
 		URL    string
 		req    *http.Request
 		reqErr error
 	)
 
-	URL = r.BaseURL + r.Endpoint
-	log.Debugf("URL: %s", URL)
+	endpoint := r.Endpoint
+	if r.PathParams != nil {
+		var err error
+		if endpoint, err = ExpandPath(endpoint, r.PathParams); err != nil {
+			logging.Get().Debug("error expanding path params", "error", err)
+			return nil, err
+		}
+	}
+	URL, reqErr = JoinURL(r.BaseURL, endpoint)
+	if reqErr != nil {
+		logging.Get().Debug("error forming request url", "error", reqErr)
+		return nil, reqErr
+	}
+	if r.Query != nil {
+		var err error
+		if URL, err = addQuery(URL, r.Query); err != nil {
+			logging.Get().Debug("error encoding query", "error", err)
+			return nil, err
+		}
+	}
+	logging.Get().Debug("forming request", "url", redactString(URL))
+
+	payload := r.Payload
+	if r.Template != nil {
+		var err error
+		if payload, err = r.Template.Render(r.Data); err != nil {
+			logging.Get().Debug("error rendering payload template", "error", err)
+			return nil, err
+		}
+	}
+	var contentType string
+	switch {
+	case payload != nil || r.Template != nil:
+	case r.Body != nil:
+		var err error
+		if payload, err = json.Marshal(r.Body); err != nil {
+			logging.Get().Debug("error encoding JSON body", "error", err)
+			return nil, fmt.Errorf("encoding request body: %w", err)
+		}
+		contentType = "application/json"
+	case r.Form != nil:
+		form, err := EncodeForm(r.Form)
+		if err != nil {
+			logging.Get().Debug("error encoding form body", "error", err)
+			return nil, err
+		}
+		payload = []byte(form.Encode())
+		contentType = ContentTypeForm
+	}
 
-	req, reqErr = http.NewRequest(r.Method, URL, bytes.NewBuffer(r.Payload))
+	req, reqErr = http.NewRequestWithContext(ctx, r.Method, URL, bytes.NewBuffer(payload))
 	if reqErr != nil {
-		log.Debugln("Error forming HTTP request")
+		logging.Get().Debug("error forming HTTP request", "error", redactErr(reqErr))
 		return nil, reqErr
 	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
 	return req, nil
 }
 
@@ -69,56 +186,297 @@ func (h Headers) AddHeader(req *http.Request) *http.Request {
 	return req
 }
 
-type NewClient http.Client
+// NewClient holds the settings used to perform requests
+// the first four fields mirror http.Client
+type NewClient struct {
+	Transport     http.RoundTripper
+	CheckRedirect func(req *http.Request, via []*http.Request) error
+	Jar           http.CookieJar
+	Timeout       time.Duration
+
+	// Profiles are named settings for differently shaped endpoints
+	// selected per request with WithProfile
+	Profiles map[string]Profile
+
+	// Live holds settings that can be reloaded without recreating the client
+	Live *LiveConfig
+
+	// Flags switches optional behaviors on and off at runtime; nil leaves them all on
+	Flags FlagProvider
+
+	// Events receives typed notifications of client activity; nil publishes nothing
+	Events *observe.EventBus
+
+	// Transformers rewrite every response body in order before it is returned,
+	// ahead of any profile transformers
+	Transformers []Transformer
+	// Retry retries transient failures; nil sends every request once
+	Retry *retry.Policy
+
+	// Breaker fast-fails requests to hosts that keep failing; nil never trips
+	Breaker *retry.Breaker
+
+	// Limiter throttles every attempt before it is sent; nil sends immediately
+	Limiter ratelimit.RequestLimiter
+
+	// Middleware wraps the transport in order, the first seeing each request
+	// first, e.g. []Middleware{auth, logging}; panics in it fail the request.
+	// the factories run once per client, on its first request, or up front in
+	// NewClientWithOptions; assign a new slice rather than changing it in place
+	Middleware []Middleware
+
+	// Hooks run around every request, e.g. for audit logging
+	Hooks Hooks
+
+	// HeaderFuncs compute headers per request by name, for requests not
+	// already carrying them, e.g. {"Accept-Language": localeFromContext}
+	HeaderFuncs map[string]HeaderFunc
+
+	// DryRun, when set, answers requests in place of Transport without touching the network
+	DryRun *DryRun
+
+	// Versioning sets the API version of every request and reports deprecation
+	// headers on responses; nil sends requests unchanged
+	Versioning *Versioning
+
+	// Logger receives the client's log output in place of the one set with SetLogger
+	Logger Logger
+
+	// Dev marks a local development client; Validate then allows InsecureSkipVerify
+	Dev bool
+}
+
+// baseTransport is what Middleware wraps: DryRun when set, else Transport
+func (c NewClient) baseTransport() http.RoundTripper {
+	if c.DryRun != nil {
+		return c.DryRun
+	}
+	return c.Transport
+}
 
 // DoRequest performs the HTTP request and return the response
+//
+// Deprecated: use Do, which returns the whole Response with its trailers and
+// timing. DoRequest keeps working and is not removed in v1
 func (c NewClient) DoRequest(req *http.Request) (*http.Response, http.Header, error) {
-	client := http.Client{Transport: c.Transport, CheckRedirect: c.CheckRedirect, Jar: c.Jar, Timeout: c.Timeout}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Errorln("Error performing HTTP request")
+	start := time.Now()
+	req, f := trackInFlight(req)
+	resp, err := c.doRequest(req)
+	if err = c.Hooks.after(req, resp, time.Since(start), f.err(err), c.logger()); err != nil {
+		if resp != nil {
+			_ = DrainBody(resp)
+		}
+		f.done()
 		return nil, nil, err
 	}
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		// upgraded connections outlive the request and keep their writable body
+		f.unlist()
+	} else {
+		resp.Body = &inFlightBody{ReadCloser: resp.Body, f: f}
+	}
 	return resp, resp.Header, nil
 }
 
+// doRequest applies the client settings to req and sends it
+func (c NewClient) doRequest(req *http.Request) (*http.Response, error) {
+	req = c.withLogger(req)
+	timeout := c.Timeout
+	profiles := c.Profiles
+	if c.Live != nil {
+		cfg := c.Live.Load()
+		var err error
+		if req, err = cfg.apply(req); err != nil {
+			return nil, err
+		}
+		if cfg.Timeout > 0 {
+			timeout = cfg.Timeout
+		}
+		if cfg.Limiter != nil {
+			// c is this request's copy, so attempt waits on the live limiter
+			c.Limiter = cfg.Limiter
+		}
+		profiles = mergeProfiles(profiles, cfg.Profiles)
+	}
+
+	transformers := c.Transformers
+	var budget time.Duration
+	if name, ok := profileName(req); ok {
+		p, ok := profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown client profile %q", name)
+		}
+		req = p.apply(req)
+		if p.Timeout > 0 {
+			timeout = p.Timeout
+		}
+		budget = p.Budget
+		transformers = append(transformers[:len(transformers):len(transformers)], p.Transformers...)
+	}
+
+	if c.Versioning != nil {
+		req = c.Versioning.apply(req)
+	}
+	var err error
+	if len(c.HeaderFuncs) > 0 {
+		if req, err = applyHeaderFuncs(req, c.HeaderFuncs); err != nil {
+			return nil, err
+		}
+	}
+
+	if req, err = c.Hooks.before(req); err != nil {
+		return nil, err
+	}
+
+	base := c.baseTransport()
+	if len(c.Middleware) > 0 {
+		if base, err = c.chain(base); err != nil {
+			return nil, err
+		}
+	}
+	client := http.Client{Transport: recoverTransport{next: base}, CheckRedirect: c.CheckRedirect, Jar: c.Jar, Timeout: timeout}
+	var b *budgetTimer
+	if budget > 0 {
+		req, b = startBudget(req, budget)
+	}
+	resp, err := c.send(&client, req, timeout)
+	if b != nil {
+		resp, err = b.finish(req, resp, err, c.Events)
+	}
+	if err != nil {
+		c.logger().Error("error performing HTTP request", "method", req.Method, "url", RedactURL(req.URL), "error", redactErr(err))
+		return nil, err
+	}
+	if d, ok := deprecation(req, resp); ok {
+		c.Events.Publish(observe.Deprecated{Time: time.Now(), Method: d.Method, URL: d.URL, Date: d.Date, Sunset: d.Sunset, Link: d.Link})
+		if c.Versioning != nil {
+			c.Versioning.report(d, c.logger())
+		}
+	}
+	if err := transform(resp, transformers); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// effectiveTimeout is the time req was allowed from start given the client timeout
+func effectiveTimeout(req *http.Request, start time.Time, timeout time.Duration) time.Duration {
+	if deadline, ok := req.Context().Deadline(); ok {
+		if d := deadline.Sub(start); timeout <= 0 || d < timeout {
+			return d
+		}
+	}
+	return timeout
+}
+
+// DoRequestCtx performs the HTTP request bound to ctx and return the response.
+// ctx replaces the request's context, so callers can cancel in-flight calls
+func (c NewClient) DoRequestCtx(ctx context.Context, req *http.Request) (*http.Response, http.Header, error) {
+	return c.DoRequest(req.WithContext(ctx))
+}
+
+// doWith performs req with c, or the default client when c is nil
+func doWith(c *NewClient, req *http.Request) (*http.Response, error) {
+	if c == nil {
+		c = sharedClient()
+	}
+	resp, _, err := c.DoRequest(req)
+	return resp, err
+}
+
 // ReadRespBody reads and return HTTP response without a buffer. Larger requests should be processed with buffers
 func ReadRespBody(resp *http.Response) ([]byte, error) {
-	body, readErr := ioutil.ReadAll(resp.Body)
-	if readErr != nil {
-		return nil, readErr
+	return readBody(resp)
+}
+
+// maxDrain caps how much of an unwanted body DrainBody reads before closing.
+// larger bodies are cheaper to abandon with their connection than to read
+const maxDrain = 64 << 10
+
+// DrainBody discards up to 64KB of an unread body and closes it so the
+// connection returns to the pool. use it on every path that does not consume
+// the body, including error paths. it is safe to call with a nil response
+func DrainBody(resp *http.Response) error {
+	if resp == nil || resp.Body == nil {
+		return nil
+	}
+	_, err := io.CopyN(io.Discard, resp.Body, maxDrain)
+	closeErr := resp.Body.Close()
+	if err == io.EOF {
+		err = nil
 	}
-	_ = resp.Body.Close() // ignore err for the linter
-	return body, nil
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// readBody is the single path responses are consumed through.
+// the body is read to EOF so the connection can be reused and is always closed
+func readBody(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// RateLimitConfig controls how ProcessStatusCode handles 429 Too Many Requests
+type RateLimitConfig struct {
+	// Block waits out the limit before returning the error; false returns at once
+	Block bool
+	// DefaultWait is used when the response has no Retry-After header
+	DefaultWait time.Duration
+	// MaxWait caps the wait; zero means no cap
+	MaxWait time.Duration
+}
+
+// RateLimit is applied by ProcessStatusCode. set it during initialization.
+// to retry rate limited requests instead, set a retry policy on the client,
+// which honors Retry-After before ProcessStatusCode sees the response
+var RateLimit = RateLimitConfig{Block: true, DefaultWait: 60 * time.Second, MaxWait: 5 * time.Minute}
+
+// RateLimitError is returned by ProcessStatusCode for 429 responses.
+// RetryAfter is how long the server asked callers to wait
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceed; retry after %s", e.RetryAfter)
 }
 
 // ProcessStatusCode process the status codes
 // 200 and 400 return a body with error
-// 429 will sleep for 60s
+// 429 waits for Retry-After, or the RateLimit default, and returns a *RateLimitError.
+// the wait ends early when the request's context is done
 // 500 returns only an error
+// with ErrorBody enabled 400 and 500 errors are a *StatusError quoting the body
 // if none of the http code categories is appropriate
 // assume a good response and return the body
 func ProcessStatusCode(r *http.Response) ([]byte, error) {
-	defer r.Body.Close()
-	body, err := io.ReadAll(r.Body)
+	body, err := readBody(r)
 	if err != nil {
-		log.Errorln("error reading http body")
+		logging.Get().Error("error reading http body", "error", err)
+		return nil, err
 	}
 
-	// switch between status code types and return body, error when necessary
+	// switch on the numeric class; the status line text is up to the server and
+	// may be localized, empty or missing its code
 	switch {
-	case strings.HasPrefix(r.Status, "2"):
+	case r.StatusCode >= 200 && r.StatusCode < 300:
 		return body, nil
 
-	case strings.HasPrefix(r.Status, "4"):
-		// sleep for 60s if rate limit exceeded
+	case r.StatusCode >= 400 && r.StatusCode < 500:
 		if r.StatusCode == http.StatusTooManyRequests {
-			time.Sleep(60 * time.Second) // sleeping now for good measure
-			return nil, errors.New("rate limit exceed")
+			return nil, rateLimited(r)
+		}
+		if ErrorBody.MaxBody > 0 {
+			return body, statusError(r, body)
 		}
-		return body, errors.New(fmt.Sprintf("Response: %v, Error: %v, Request: %v", string(body), err, r.Request))
+		return body, errors.New(fmt.Sprintf("Response: %v, Request: %v", string(body), r.Request))
 
-	case strings.HasPrefix(r.Status, "5"):
+	case r.StatusCode >= 500 && r.StatusCode < 600:
+		if ErrorBody.MaxBody > 0 {
+			return nil, statusError(r, body)
+		}
 		return nil, errors.New("50X received; check network/service availability")
 
 	// catch all in case of an odd status code
@@ -128,11 +486,49 @@ func ProcessStatusCode(r *http.Response) ([]byte, error) {
 
 }
 
+// statusError describes a 4xx or 5xx response with its already read body
+func statusError(r *http.Response, body []byte) *StatusError {
+	return &StatusError{StatusCode: r.StatusCode, Status: r.Status, ContentType: r.Header.Get("Content-Type"), Body: body}
+}
+
+// rateLimited applies RateLimit to a 429 response
+func rateLimited(r *http.Response) error {
+	cfg := RateLimit
+	wait, ok := retry.ParseRetryAfter(r.Header, time.Now())
+	if !ok {
+		wait = cfg.DefaultWait
+	}
+	if cfg.MaxWait > 0 && wait > cfg.MaxWait {
+		wait = cfg.MaxWait
+	}
+	rlErr := &RateLimitError{RetryAfter: wait}
+	if !cfg.Block || wait <= 0 {
+		return rlErr
+	}
+
+	ctx := context.Background()
+	if r.Request != nil {
+		ctx = r.Request.Context()
+	}
+	if err := sleepCtx(ctx, wait); err != nil {
+		return err
+	}
+	return rlErr
+}
+
 // DefaultRequest provides a standardized way to perform HTTP calls
+//
+// Deprecated: use Send, which takes a context and returns the whole Response.
+// DefaultRequest keeps working and is not removed in v1
 func DefaultRequest(req *FormRequest, headers []Headers) ([]byte, error) {
-	r, err := req.FormRequest()
+	return DefaultRequestWithContext(context.Background(), req, headers)
+}
+
+// DefaultRequestWithContext is DefaultRequest bound to ctx
+func DefaultRequestWithContext(ctx context.Context, req *FormRequest, headers []Headers) ([]byte, error) {
+	r, err := req.FormRequestWithContext(ctx)
 	if err != nil {
-		log.Errorln("Incorrect parameters set in form request")
+		logging.Get().Error("incorrect parameters set in form request", "error", err)
 		return nil, err
 	}
 
@@ -0,0 +1,116 @@
+package httplib
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestProcessStatusCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     int
+		status   string
+		header   http.Header
+		wantBody bool
+		wantErr  bool
+		rate     bool
+	}{
+		{name: "ok", code: 200, status: "200 OK", wantBody: true},
+		{name: "localized ok", code: 200, status: "200 Tamam", wantBody: true},
+		{name: "empty reason", code: 201, status: "201 ", wantBody: true},
+		{name: "empty status line", code: 204, status: "", wantBody: true},
+		{name: "code-less status", code: 200, status: "OK", wantBody: true},
+		{name: "misleading reason", code: 200, status: "200 Internal Server Error", wantBody: true},
+		{name: "localized not found", code: 404, status: "404 Nicht Gefunden", wantBody: true, wantErr: true},
+		{name: "code-less bad request", code: 400, status: "Bad Request", wantBody: true, wantErr: true},
+		{name: "localized server error", code: 503, status: "503 Service Indisponible", wantErr: true},
+		{name: "empty server error", code: 500, status: "", wantErr: true},
+		{name: "informational", code: 103, status: "103 Early Hints", wantBody: true},
+		{name: "redirect", code: 304, status: "304 Not Modified", wantBody: true},
+		{name: "odd code", code: 299, status: "299 Whatever", wantBody: true},
+		{name: "rate limited", code: 429, status: "429 Zu Viele", header: http.Header{"Retry-After": {"0"}}, wantErr: true, rate: true},
+		{name: "code-less rate limited", code: 429, status: "", header: http.Header{"Retry-After": {"0"}}, wantErr: true, rate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+			resp := &http.Response{
+				StatusCode: tt.code,
+				Status:     tt.status,
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader("payload")),
+			}
+			body, err := ProcessStatusCode(resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %t", err, tt.wantErr)
+			}
+			if got := string(body) == "payload"; got != tt.wantBody {
+				t.Errorf("body = %q, want body %t", body, tt.wantBody)
+			}
+			var rlErr *RateLimitError
+			if errors.As(err, &rlErr) != tt.rate {
+				t.Errorf("err = %v, want *RateLimitError %t", err, tt.rate)
+			}
+		})
+	}
+}
+
+func TestProcessStatusCodeWireStatusLines(t *testing.T) {
+	tests := []struct {
+		line    string
+		wantErr bool
+	}{
+		{"HTTP/1.1 200 D'accord", false},
+		{"HTTP/1.1 200", false},
+		{"HTTP/1.1 200 ", false},
+		{"HTTP/1.1 404 Introuvable", true},
+		{"HTTP/1.1 502", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			url := rawStatusServer(t, tt.line)
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultTransport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = ProcessStatusCode(resp)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// rawStatusServer answers one request with line as the status line
+func rawStatusServer(t *testing.T, line string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err := http.ReadRequest(bufio.NewReader(conn)); err != nil {
+			return
+		}
+		_, _ = io.WriteString(conn, line+"\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok")
+	}()
+	return "http://" + ln.Addr().String()
+}
@@ -0,0 +1,20 @@
+module github.com/clairmont32/httplib/httplibotel
+
+go 1.18
+
+require (
+	github.com/clairmont32/httplib v0.0.0
+	go.opentelemetry.io/otel v1.14.0
+	go.opentelemetry.io/otel/trace v1.14.0
+)
+
+require (
+	github.com/go-logr/logr v1.2.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/sirupsen/logrus v1.8.1 // indirect
+	golang.org/x/sync v0.1.0 // indirect
+	golang.org/x/sys v0.1.0 // indirect
+	golang.org/x/text v0.4.0 // indirect
+)
+
+replace github.com/clairmont32/httplib => ../
@@ -0,0 +1,136 @@
+// Package httplibotel creates OpenTelemetry client spans for requests sent
+// through httplib and propagates the trace context to upstreams. it is a
+// separate module so the core never depends on OpenTelemetry
+//
+//	c := httplib.NewClient{Timeout: 10 * time.Second, Middleware: []httplib.Middleware{httplibotel.Middleware(httplibotel.Config{})}}
+package httplibotel
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/clairmont32/httplib"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the tracer of this package
+const instrumentationName = "github.com/clairmont32/httplib/httplibotel"
+
+// Config selects where spans go and how context is propagated
+type Config struct {
+	// TracerProvider creates the tracer; nil uses the global provider
+	TracerProvider trace.TracerProvider
+	// Propagator injects the trace context into request headers; nil uses the
+	// global propagator
+	Propagator propagation.TextMapPropagator
+	// SpanName names spans; nil uses "HTTP <method>"
+	SpanName func(req *http.Request) string
+}
+
+// Middleware returns a httplib.Middleware creating one span per attempt, so
+// retries show up as separate spans of the caller's trace
+func Middleware(cfg Config) httplib.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return NewTransport(next, cfg)
+	}
+}
+
+// NewTransport wraps next with tracing for clients built without httplib.
+// a nil next uses http.DefaultTransport
+func NewTransport(next http.RoundTripper, cfg Config) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	tp := cfg.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &transport{next: next, cfg: cfg, tracer: tp.Tracer(instrumentationName)}
+}
+
+type transport struct {
+	next   http.RoundTripper
+	cfg    Config
+	tracer trace.Tracer
+}
+
+// RoundTrip sends req in a client span that ends once the body is read or closed
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name := "HTTP " + req.Method
+	if t.cfg.SpanName != nil {
+		name = t.cfg.SpanName(req)
+	}
+	attrs := []attribute.KeyValue{
+		semconv.HTTPMethod(req.Method),
+		semconv.HTTPURL(httplib.RedactURL(req.URL)),
+		semconv.NetPeerName(req.URL.Hostname()),
+	}
+	if port := req.URL.Port(); port != "" {
+		if p, err := strconv.Atoi(port); err == nil {
+			attrs = append(attrs, semconv.NetPeerPort(p))
+		}
+	}
+	if tags := httplib.RequestTags(req); len(tags) > 0 {
+		attrs = append(attrs, attribute.StringSlice("httplib.tags", tags))
+	}
+	ctx, span := t.tracer.Start(req.Context(), name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+
+	r := req.Clone(ctx)
+	prop := t.cfg.Propagator
+	if prop == nil {
+		prop = otel.GetTextMapPropagator()
+	}
+	prop.Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	resp, err := t.next.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	if resp.Body == nil || resp.StatusCode == http.StatusSwitchingProtocols {
+		span.End()
+		return resp, nil
+	}
+	resp.Body = &spanBody{ReadCloser: resp.Body, span: span}
+	return resp, nil
+}
+
+// spanBody ends the span when the body hits EOF, fails or is closed
+type spanBody struct {
+	io.ReadCloser
+	span trace.Span
+	once sync.Once
+}
+
+func (b *spanBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.once.Do(func() {
+			if err != io.EOF {
+				b.span.RecordError(err)
+				b.span.SetStatus(codes.Error, err.Error())
+			}
+			b.span.End()
+		})
+	}
+	return n, err
+}
+
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.span.End() })
+	return err
+}
@@ -0,0 +1,189 @@
+// Package httplibprom records httplib client metrics for Prometheus. it is a
+// separate module so the core never depends on the Prometheus client
+//
+//	m := httplibprom.NewCollector(httplibprom.Config{})
+//	prometheus.MustRegister(m)
+//	var bus observe.EventBus
+//	bus.Handle(m.Observe)
+//	c := httplib.NewClient{Events: &bus, Middleware: []httplib.Middleware{m.Middleware()}}
+package httplibprom
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/clairmont32/httplib"
+	"github.com/clairmont32/httplib/observe"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Config names and buckets the metrics
+type Config struct {
+	// Namespace prefixes every metric name; empty uses "httplib"
+	Namespace string
+	// ConstLabels are added to every metric, e.g. the name of the upstream service
+	ConstLabels prometheus.Labels
+	// DurationBuckets are the latency histogram buckets in seconds; nil uses prometheus.DefBuckets
+	DurationBuckets []float64
+	// SizeBuckets are the response size histogram buckets in bytes; nil uses
+	// powers of four from 256B to 64MB
+	SizeBuckets []float64
+}
+
+// Collector holds the client metrics. register it with a prometheus.Registerer,
+// feed it the client events with Observe and add Middleware for response sizes.
+// every attempt of a retried request is counted on its own
+type Collector struct {
+	requests *prometheus.CounterVec
+	inFlight *prometheus.GaugeVec
+	duration *prometheus.HistogramVec
+	size     *prometheus.HistogramVec
+	retries  *prometheus.CounterVec
+}
+
+// NewCollector returns a Collector with the metrics
+//
+//	httplib_requests_total{host,method,status_class}
+//	httplib_requests_in_flight{host,method}
+//	httplib_request_duration_seconds{host,method,status_class}
+//	httplib_response_size_bytes{host,method,status_class}
+//	httplib_retries_total{host,method}
+//
+// status_class is "2xx" to "5xx", or "error" for requests that got no response
+func NewCollector(cfg Config) *Collector {
+	ns := cfg.Namespace
+	if ns == "" {
+		ns = "httplib"
+	}
+	durations := cfg.DurationBuckets
+	if durations == nil {
+		durations = prometheus.DefBuckets
+	}
+	sizes := cfg.SizeBuckets
+	if sizes == nil {
+		sizes = prometheus.ExponentialBuckets(256, 4, 10)
+	}
+	byStatus := []string{"host", "method", "status_class"}
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "requests_total", ConstLabels: cfg.ConstLabels,
+			Help: "Requests sent, counting every attempt.",
+		}, byStatus),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: ns, Name: "requests_in_flight", ConstLabels: cfg.ConstLabels,
+			Help: "Requests waiting for their response headers.",
+		}, []string{"host", "method"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Name: "request_duration_seconds", ConstLabels: cfg.ConstLabels, Buckets: durations,
+			Help: "Time until the response headers arrived or the request failed.",
+		}, byStatus),
+		size: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Name: "response_size_bytes", ConstLabels: cfg.ConstLabels, Buckets: sizes,
+			Help: "Response body bytes read by the caller.",
+		}, byStatus),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "retries_total", ConstLabels: cfg.ConstLabels,
+			Help: "Retries scheduled after failed attempts.",
+		}, []string{"host", "method"}),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.inFlight.Describe(ch)
+	c.duration.Describe(ch)
+	c.size.Describe(ch)
+	c.retries.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.inFlight.Collect(ch)
+	c.duration.Collect(ch)
+	c.size.Collect(ch)
+	c.retries.Collect(ch)
+}
+
+// Observe records a client event; pass it to EventBus.Handle
+func (c *Collector) Observe(e observe.Event) {
+	switch e := e.(type) {
+	case observe.RequestStarted:
+		c.inFlight.WithLabelValues(host(e.URL), e.Method).Inc()
+	case observe.RequestFinished:
+		h := host(e.URL)
+		c.inFlight.WithLabelValues(h, e.Method).Dec()
+		class := statusClass(e.Status)
+		if e.Err != nil {
+			class = "error"
+		}
+		c.requests.WithLabelValues(h, e.Method, class).Inc()
+		c.duration.WithLabelValues(h, e.Method, class).Observe(e.Duration.Seconds())
+	case observe.RetryScheduled:
+		c.retries.WithLabelValues(host(e.URL), e.Method).Inc()
+	}
+}
+
+// Middleware returns a httplib.Middleware recording response sizes once a
+// body is read to the end or closed
+func (c *Collector) Middleware() httplib.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &sizeTransport{next: next, size: c.size}
+	}
+}
+
+type sizeTransport struct {
+	next http.RoundTripper
+	size *prometheus.HistogramVec
+}
+
+func (t *sizeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.Body == nil || resp.StatusCode == http.StatusSwitchingProtocols {
+		return resp, err
+	}
+	resp.Body = &sizeBody{ReadCloser: resp.Body, observer: t.size.WithLabelValues(req.URL.Host, req.Method, statusClass(resp.StatusCode))}
+	return resp, nil
+}
+
+// host returns the host label of an event URL
+func host(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// sizeBody counts the bytes read and observes them once
+type sizeBody struct {
+	io.ReadCloser
+	observer prometheus.Observer
+	n        int64
+	once     sync.Once
+}
+
+func (b *sizeBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err != nil {
+		b.once.Do(func() { b.observer.Observe(float64(b.n)) })
+	}
+	return n, err
+}
+
+func (b *sizeBody) Close() error {
+	b.once.Do(func() { b.observer.Observe(float64(b.n)) })
+	return b.ReadCloser.Close()
+}
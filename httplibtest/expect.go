@@ -0,0 +1,108 @@
+package httplibtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// ErrUnexpectedRequest is returned, wrapped, for requests no expectation
+// matches when the MockTransport has no Responder to fall back on
+var ErrUnexpectedRequest = errors.New("unexpected request")
+
+// Expectation is a request a test expects, registered with MockTransport.On,
+// and the reply it gets
+type Expectation struct {
+	matcher Matcher
+	resp    Response
+	times   int // 0 means any number, at least once
+	calls   int
+}
+
+// On registers an expectation for requests matching all matchers and returns
+// it for configuring the reply. expectations are tried in registration order
+// and replace the Responder for the requests they match
+//
+//	m.On(httplibtest.Method("GET"), httplibtest.Path("/users/1")).ReplyJSON(200, user).Once()
+func (m *MockTransport) On(matchers ...Matcher) *Expectation {
+	e := &Expectation{matcher: All(matchers...), resp: Response{Status: http.StatusOK}}
+	m.mu.Lock()
+	m.expectations = append(m.expectations, e)
+	m.mu.Unlock()
+	return e
+}
+
+// Reply serves r to matching requests
+func (e *Expectation) Reply(r Response) *Expectation {
+	e.resp = r
+	return e
+}
+
+// ReplyJSON serves v encoded as JSON with status
+func (e *Expectation) ReplyJSON(status int, v interface{}) *Expectation {
+	body, err := json.Marshal(v)
+	if err != nil {
+		e.resp = Response{Err: fmt.Errorf("encoding reply: %w", err)}
+		return e
+	}
+	e.resp = Response{Status: status, Header: http.Header{"Content-Type": {"application/json"}}, Body: body}
+	return e
+}
+
+// Fail returns err to matching requests instead of a response
+func (e *Expectation) Fail(err error) *Expectation {
+	e.resp = Response{Err: err}
+	return e
+}
+
+// Times makes the expectation match exactly n requests; later ones fall through
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Once is Times(1)
+func (e *Expectation) Once() *Expectation {
+	return e.Times(1)
+}
+
+// String describes the expectation for failure messages
+func (e *Expectation) String() string {
+	return "[" + e.matcher.String() + "]"
+}
+
+// respond returns the reply of the first open expectation matching c
+func (m *MockTransport) respond(c Call) (Response, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.expectations {
+		if (e.times == 0 || e.calls < e.times) && e.matcher.match(c) {
+			e.calls++
+			return e.resp, true
+		}
+	}
+	return Response{}, false
+}
+
+// AssertExpectations fails the test for every expectation that was not met:
+// called exactly its Times, or at least once without Times
+func (m *MockTransport) AssertExpectations(t testing.TB) bool {
+	t.Helper()
+	m.mu.Lock()
+	expectations := append([]*Expectation(nil), m.expectations...)
+	m.mu.Unlock()
+	ok := true
+	for _, e := range expectations {
+		switch {
+		case e.times == 0 && e.calls == 0:
+			t.Errorf("expected a call matching %s\n%s", e, m.describe())
+			ok = false
+		case e.times > 0 && e.calls != e.times:
+			t.Errorf("expected %d calls matching %s, got %d\n%s", e.times, e, e.calls, m.describe())
+			ok = false
+		}
+	}
+	return ok
+}
@@ -0,0 +1,110 @@
+package httplibtest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/clairmont32/httplib/retry"
+)
+
+// FaultTransport wraps Next and injects failures at the configured rates, for
+// checking retry and timeout handling under chaos. each rate is a probability
+// from 0 to 1 rolled independently per request. a nil Next uses http.DefaultTransport
+//
+//	rt := &httplibtest.FaultTransport{ErrorRate: 0.2, DropRate: 0.05, Latency: time.Second, LatencyRate: 0.1, Rand: retry.NewSource(1)}
+type FaultTransport struct {
+	Next http.RoundTripper
+
+	// Latency delays the request by up to this long at LatencyRate; the wait
+	// honors the request context
+	Latency     time.Duration
+	LatencyRate float64
+
+	// DropRate fails the request with ErrConnReset without sending it
+	DropRate float64
+
+	// ErrorRate replies with one of ErrorStatuses without sending the request;
+	// nil ErrorStatuses use 503
+	ErrorRate     float64
+	ErrorStatuses []int
+
+	// TruncateRate cuts the response body at a random point, after which reads
+	// fail with io.ErrUnexpectedEOF
+	TruncateRate float64
+
+	// Rand makes the faults reproducible; nil uses the global math/rand source
+	Rand retry.Source
+}
+
+// RoundTrip sends req through Next unless a fault replaces it
+func (f *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := f.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if f.roll(f.LatencyRate) {
+		if err := sleep(req.Context(), time.Duration(f.float()*float64(f.Latency))); err != nil {
+			return nil, err
+		}
+	}
+	if f.roll(f.DropRate) {
+		closeBody(req)
+		return nil, ErrConnReset
+	}
+	if f.roll(f.ErrorRate) {
+		closeBody(req)
+		status := http.StatusServiceUnavailable
+		if len(f.ErrorStatuses) > 0 {
+			status = f.ErrorStatuses[int(f.float()*float64(len(f.ErrorStatuses)))%len(f.ErrorStatuses)]
+		}
+		return Response{Status: status, Body: []byte(fmt.Sprintf("injected fault: %d %s", status, http.StatusText(status)))}.build(req), nil
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil || !f.roll(f.TruncateRate) {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &truncatedBody{r: bytes.NewReader(body[:int(f.float()*float64(len(body)))])}
+	return resp, nil
+}
+
+func (f *FaultTransport) roll(rate float64) bool {
+	return rate > 0 && f.float() < rate
+}
+
+func (f *FaultTransport) float() float64 {
+	if f.Rand != nil {
+		return f.Rand.Float64()
+	}
+	return rand.Float64()
+}
+
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
+
+// truncatedBody ends in io.ErrUnexpectedEOF like a connection closed mid-body
+type truncatedBody struct {
+	r *bytes.Reader
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (b *truncatedBody) Close() error { return nil }
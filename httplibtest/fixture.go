@@ -0,0 +1,90 @@
+package httplibtest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// LoadFixture builds a canned Response from a file, usually under testdata.
+// the file is first rendered as a text/template with vars, so fixtures can
+// reference values like {{.ID}}; pass nil when the file has no variables
+//
+// .json files are served as a 200 with Content-Type application/json.
+// any other file is parsed as a raw HTTP response: status line, headers,
+// a blank line and the body. Content-Length is recomputed from the rendered body
+func LoadFixture(path string, vars map[string]interface{}) (Response, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Response{}, err
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(string(raw))
+	if err != nil {
+		return Response{}, fmt.Errorf("fixture %s: %w", path, err)
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, vars); err != nil {
+		return Response{}, fmt.Errorf("fixture %s: %w", path, err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return Response{
+			Status: http.StatusOK,
+			Header: http.Header{"Content-Type": {"application/json"}},
+			Body:   rendered.Bytes(),
+		}, nil
+	}
+
+	resp, err := parseRawResponse(rendered.Bytes())
+	if err != nil {
+		return Response{}, fmt.Errorf("fixture %s: %w", path, err)
+	}
+	return resp, nil
+}
+
+// MustLoadFixture is LoadFixture that fails the test on error
+func MustLoadFixture(t testing.TB, path string, vars map[string]interface{}) Response {
+	t.Helper()
+	resp, err := LoadFixture(path, vars)
+	if err != nil {
+		t.Fatalf("loading fixture: %v", err)
+	}
+	return resp
+}
+
+// parseRawResponse splits a raw HTTP response into head and body.
+// the body is taken verbatim so templated fixtures never disagree with Content-Length
+func parseRawResponse(raw []byte) (Response, error) {
+	raw = bytes.TrimLeft(raw, "\r\n")
+	head, body := raw, []byte(nil)
+	// split at the first blank line in either style; the body may hold the other
+	at := -1
+	for _, sep := range []string{"\r\n\r\n", "\n\n"} {
+		if i := bytes.Index(raw, []byte(sep)); i >= 0 && (at < 0 || i < at) {
+			at = i
+			head, body = raw[:i], raw[i+len(sep):]
+		}
+	}
+
+	// terminate the head so http.ReadResponse stops at the headers
+	head = append(append([]byte{}, head...), "\r\n\r\n"...)
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(head)), nil)
+	if err != nil {
+		return Response{}, err
+	}
+	_ = resp.Body.Close()
+	resp.Header.Del("Content-Length")
+
+	return Response{
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   body,
+	}, nil
+}
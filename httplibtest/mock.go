@@ -0,0 +1,407 @@
+// Package httplibtest provides test doubles for code built on httplib.
+// MockTransport plugs into NewClient.Transport, records every request sent
+// through it and replies with canned responses so tests never touch the network
+package httplibtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Response is a canned reply served by MockTransport
+// when Err is set it is returned instead of a response
+//
+// Delay holds the response headers back, ChunkSize and ChunkDelay dribble
+// the body out in small writes, and Reset drops the connection once ResetAfter
+// body bytes were read. all waits honor the request context so client
+// timeouts and cancellation behave as they would against a slow server
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	Err    error
+
+	Delay      time.Duration
+	ChunkSize  int
+	ChunkDelay time.Duration
+	Reset      bool
+	ResetAfter int
+}
+
+// Call is a captured outbound request
+type Call struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+}
+
+// DecodeJSON unmarshals the captured request body into v
+func (c Call) DecodeJSON(v interface{}) error {
+	return json.Unmarshal(c.Body, v)
+}
+
+// String renders the call for failure messages
+func (c Call) String() string {
+	return fmt.Sprintf("%s %s", c.Method, c.URL)
+}
+
+// MockTransport is a http.RoundTripper that records requests
+// expectations registered with On reply first; Responder picks the reply for
+// the other requests. with neither, requests get a 200 with an empty body, and
+// with expectations but no Responder unmatched requests fail
+type MockTransport struct {
+	Responder func(req *http.Request) Response
+
+	mu           sync.Mutex
+	calls        []Call
+	expectations []*Expectation
+}
+
+// RoundTrip records req and returns the canned response
+func (m *MockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	call, err := capture(req)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.calls = append(m.calls, call)
+	m.mu.Unlock()
+
+	r, ok := m.respond(call)
+	switch {
+	case ok:
+	case m.Responder != nil:
+		r = m.Responder(req)
+	case m.hasExpectations():
+		return nil, fmt.Errorf("%w: no expectation matches", ErrUnexpectedRequest)
+	default:
+		r = Response{Status: http.StatusOK}
+	}
+	if r.Delay > 0 {
+		if err := sleep(req.Context(), r.Delay); err != nil {
+			return nil, err
+		}
+	}
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.build(req), nil
+}
+
+// Calls returns a copy of every request received, oldest first
+func (m *MockTransport) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Call, len(m.calls))
+	copy(out, m.calls)
+	return out
+}
+
+// Reset forgets all recorded calls
+func (m *MockTransport) Reset() {
+	m.mu.Lock()
+	m.calls = nil
+	m.mu.Unlock()
+}
+
+func (m *MockTransport) hasExpectations() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.expectations) > 0
+}
+
+// build turns the canned response into a *http.Response for req
+func (r Response) build(req *http.Request) *http.Response {
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	header := r.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          r.body(req.Context()),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// body returns a plain reader unless the response shapes its delivery
+func (r Response) body(ctx context.Context) io.ReadCloser {
+	if r.ChunkSize <= 0 && r.ChunkDelay <= 0 && !r.Reset {
+		return io.NopCloser(bytes.NewReader(r.Body))
+	}
+	return &shapedBody{ctx: ctx, resp: r, data: r.Body}
+}
+
+// ErrConnReset is returned by body reads of responses with Reset set.
+// it wraps syscall.ECONNRESET so errors.Is checks written for real sockets work
+var ErrConnReset error = &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+
+// shapedBody serves a canned body in chunks with optional pauses and a reset
+type shapedBody struct {
+	ctx  context.Context
+	resp Response
+	data []byte
+	read int
+}
+
+func (b *shapedBody) Read(p []byte) (int, error) {
+	if b.resp.Reset && b.read >= b.resp.ResetAfter {
+		return 0, ErrConnReset
+	}
+	if len(b.data) == 0 {
+		return 0, io.EOF
+	}
+	if b.resp.ChunkDelay > 0 && b.read > 0 {
+		if err := sleep(b.ctx, b.resp.ChunkDelay); err != nil {
+			return 0, err
+		}
+	}
+
+	n := len(p)
+	if b.resp.ChunkSize > 0 && n > b.resp.ChunkSize {
+		n = b.resp.ChunkSize
+	}
+	if b.resp.Reset && n > b.resp.ResetAfter-b.read {
+		n = b.resp.ResetAfter - b.read
+	}
+	n = copy(p[:n], b.data)
+	b.data = b.data[n:]
+	b.read += n
+	return n, nil
+}
+
+func (b *shapedBody) Close() error {
+	return nil
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// capture copies the parts of req tests assert on and restores its body
+func capture(req *http.Request) (Call, error) {
+	u := *req.URL
+	call := Call{
+		Method: req.Method,
+		URL:    &u,
+		Header: req.Header.Clone(),
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return Call{}, err
+		}
+		call.Body = body
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return call, nil
+}
+
+// Matcher selects recorded calls in assertions
+type Matcher struct {
+	desc  string
+	match func(Call) bool
+}
+
+// String describes the matcher for failure messages
+func (m Matcher) String() string {
+	return m.desc
+}
+
+// Match reports whether c satisfies the matcher
+func (m Matcher) Match(c Call) bool {
+	return m.match(c)
+}
+
+// Method matches calls using the given HTTP method
+func Method(method string) Matcher {
+	return Matcher{
+		desc:  "method " + method,
+		match: func(c Call) bool { return strings.EqualFold(c.Method, method) },
+	}
+}
+
+// Path matches calls whose URL path equals path
+func Path(path string) Matcher {
+	return Matcher{
+		desc:  "path " + path,
+		match: func(c Call) bool { return c.URL.Path == path },
+	}
+}
+
+// Query matches calls with the query parameter key set to value
+func Query(key, value string) Matcher {
+	return Matcher{
+		desc: fmt.Sprintf("query %s=%s", key, value),
+		match: func(c Call) bool {
+			for _, v := range c.URL.Query()[key] {
+				if v == value {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// Header matches calls carrying the header key with value
+func Header(key, value string) Matcher {
+	return Matcher{
+		desc: fmt.Sprintf("header %s: %s", key, value),
+		match: func(c Call) bool {
+			for _, v := range c.Header.Values(key) {
+				if v == value {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// JSONBody matches calls whose body decodes to the same JSON value as want
+func JSONBody(want interface{}) Matcher {
+	wantJSON, err := json.Marshal(want)
+	return Matcher{
+		desc: "json body " + string(wantJSON),
+		match: func(c Call) bool {
+			if err != nil {
+				return false
+			}
+			return jsonEqual(c.Body, wantJSON)
+		},
+	}
+}
+
+// All matches calls satisfying every matcher
+func All(matchers ...Matcher) Matcher {
+	descs := make([]string, len(matchers))
+	for i, m := range matchers {
+		descs[i] = m.desc
+	}
+	return Matcher{
+		desc: strings.Join(descs, ", "),
+		match: func(c Call) bool {
+			for _, m := range matchers {
+				if !m.match(c) {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+// AssertCalled fails the test unless at least one call matches all matchers
+func (m *MockTransport) AssertCalled(t testing.TB, matchers ...Matcher) bool {
+	t.Helper()
+	if m.count(All(matchers...)) == 0 {
+		t.Errorf("expected a call matching [%s]\n%s", All(matchers...), m.describe())
+		return false
+	}
+	return true
+}
+
+// AssertNotCalled fails the test if any call matches all matchers
+func (m *MockTransport) AssertNotCalled(t testing.TB, matchers ...Matcher) bool {
+	t.Helper()
+	if n := m.count(All(matchers...)); n != 0 {
+		t.Errorf("expected no calls matching [%s], got %d\n%s", All(matchers...), n, m.describe())
+		return false
+	}
+	return true
+}
+
+// AssertCallCount fails the test unless exactly want calls match all matchers
+func (m *MockTransport) AssertCallCount(t testing.TB, want int, matchers ...Matcher) bool {
+	t.Helper()
+	if got := m.count(All(matchers...)); got != want {
+		t.Errorf("expected %d calls matching [%s], got %d\n%s", want, All(matchers...), got, m.describe())
+		return false
+	}
+	return true
+}
+
+// AssertOrder fails the test unless calls matching each step were made in the given order
+// other calls may be interleaved between the steps
+func (m *MockTransport) AssertOrder(t testing.TB, steps ...Matcher) bool {
+	t.Helper()
+	calls := m.Calls()
+	next := 0
+	for _, c := range calls {
+		if next < len(steps) && steps[next].match(c) {
+			next++
+		}
+	}
+	if next < len(steps) {
+		t.Errorf("expected calls in order, step %d [%s] not satisfied\n%s", next+1, steps[next], m.describe())
+		return false
+	}
+	return true
+}
+
+func (m *MockTransport) count(matcher Matcher) int {
+	n := 0
+	for _, c := range m.Calls() {
+		if matcher.match(c) {
+			n++
+		}
+	}
+	return n
+}
+
+// describe lists recorded calls for failure messages
+func (m *MockTransport) describe() string {
+	calls := m.Calls()
+	if len(calls) == 0 {
+		return "recorded calls: none"
+	}
+	var b strings.Builder
+	b.WriteString("recorded calls:")
+	for i, c := range calls {
+		fmt.Fprintf(&b, "\n  %d. %s", i+1, c)
+	}
+	return b.String()
+}
+
+func jsonEqual(a, b []byte) bool {
+	var av, bv interface{}
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return false
+	}
+	ac, _ := json.Marshal(av)
+	bc, _ := json.Marshal(bv)
+	return bytes.Equal(ac, bc)
+}
@@ -0,0 +1,166 @@
+package httplib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InFlightRequest is a request still executing: waiting for a connection,
+// retries or its response, or with its body not yet read to the end or closed
+type InFlightRequest struct {
+	Method  string
+	URL     string // redacted like logs
+	Host    string
+	Started time.Time
+	Elapsed time.Duration
+	Tags    []string
+}
+
+// TagCanceledError is returned for requests aborted by CancelTag. it unwraps
+// to context.Canceled so existing cancellation checks keep working
+type TagCanceledError struct {
+	Tag string
+}
+
+func (e *TagCanceledError) Error() string {
+	return fmt.Sprintf("request cancelled by tag %q", e.Tag)
+}
+
+func (e *TagCanceledError) Unwrap() error { return context.Canceled }
+
+var inflight struct {
+	mu   sync.Mutex
+	next uint64
+	reqs map[uint64]*inFlightEntry
+}
+
+type inFlightEntry struct {
+	info   InFlightRequest
+	cancel context.CancelFunc
+	// tag is the tag the request was cancelled by, guarded by inflight.mu
+	tag string
+}
+
+// InFlight returns every request executing through any client, longest running
+// first, for operator endpoints showing what a service is waiting on
+func InFlight() []InFlightRequest {
+	now := time.Now()
+	inflight.mu.Lock()
+	out := make([]InFlightRequest, 0, len(inflight.reqs))
+	for _, e := range inflight.reqs {
+		r := e.info
+		r.Elapsed = now.Sub(r.Started)
+		out = append(out, r)
+	}
+	inflight.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Started.Before(out[j].Started) })
+	return out
+}
+
+// InFlightByHost is InFlight grouped by host
+func InFlightByHost() map[string][]InFlightRequest {
+	byHost := make(map[string][]InFlightRequest)
+	for _, r := range InFlight() {
+		byHost[r.Host] = append(byHost[r.Host], r)
+	}
+	return byHost
+}
+
+// CancelTag aborts every in-flight request carrying tag, e.g. all calls of an
+// export the user gave up on, and returns how many were cancelled. they fail,
+// or their bodies stop reading, with a *TagCanceledError. requests sent later
+// with the tag are not affected
+func CancelTag(tag string) int {
+	inflight.mu.Lock()
+	defer inflight.mu.Unlock()
+	n := 0
+	for _, e := range inflight.reqs {
+		if e.tag != "" || !containsTag(e.info.Tags, tag) {
+			continue
+		}
+		e.tag = tag
+		e.cancel()
+		n++
+	}
+	return n
+}
+
+// inFlight is the registration of one request from trackInFlight
+type inFlight struct {
+	id    uint64
+	entry *inFlightEntry
+	once  sync.Once
+}
+
+// trackInFlight registers req and returns it bound to a context CancelTag can cancel
+func trackInFlight(req *http.Request) (*http.Request, *inFlight) {
+	ctx, cancel := context.WithCancel(req.Context())
+	e := &inFlightEntry{
+		info:   InFlightRequest{Method: req.Method, URL: RedactURL(req.URL), Host: req.URL.Host, Started: time.Now(), Tags: RequestTags(req)},
+		cancel: cancel,
+	}
+	inflight.mu.Lock()
+	if inflight.reqs == nil {
+		inflight.reqs = make(map[uint64]*inFlightEntry)
+	}
+	f := &inFlight{id: inflight.next, entry: e}
+	inflight.next++
+	inflight.reqs[f.id] = e
+	inflight.mu.Unlock()
+	return req.WithContext(ctx), f
+}
+
+// err returns a *TagCanceledError in place of err when CancelTag aborted the request
+func (f *inFlight) err(err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	inflight.mu.Lock()
+	tag := f.entry.tag
+	inflight.mu.Unlock()
+	if tag == "" {
+		return err
+	}
+	return &TagCanceledError{Tag: tag}
+}
+
+// done unlists the request and releases its context
+func (f *inFlight) done() {
+	f.unlist()
+	f.entry.cancel()
+}
+
+// unlist removes the request from InFlight and from the reach of CancelTag
+// while keeping its context alive, for upgraded connections outliving the request
+func (f *inFlight) unlist() {
+	f.once.Do(func() {
+		inflight.mu.Lock()
+		delete(inflight.reqs, f.id)
+		inflight.mu.Unlock()
+	})
+}
+
+// inFlightBody keeps a request listed until its body is read to the end or closed
+type inFlightBody struct {
+	io.ReadCloser
+	f *inFlight
+}
+
+func (b *inFlightBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		err = b.f.err(err)
+		b.f.done()
+	}
+	return n, err
+}
+
+func (b *inFlightBody) Close() error {
+	b.f.done()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,43 @@
+// Package logging holds the Logger every httplib package writes through, so
+// the core and the leaf packages share one setting without importing each other
+package logging
+
+import "sync/atomic"
+
+// Logger takes a message and alternating keys and values. *slog.Logger
+// satisfies it as is
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// Nop discards everything
+type Nop struct{}
+
+func (Nop) Debug(string, ...interface{}) {}
+func (Nop) Info(string, ...interface{})  {}
+func (Nop) Warn(string, ...interface{})  {}
+func (Nop) Error(string, ...interface{}) {}
+
+// holder keeps atomic.Value storing one concrete type
+type holder struct{ l Logger }
+
+var current atomic.Value // holder
+
+// Set makes l the package wide logger; nil discards logs
+func Set(l Logger) {
+	if l == nil {
+		l = Nop{}
+	}
+	current.Store(holder{l})
+}
+
+// Get returns the package wide logger
+func Get() Logger {
+	if h, ok := current.Load().(holder); ok {
+		return h.l
+	}
+	return Nop{}
+}
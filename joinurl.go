@@ -0,0 +1,64 @@
+package httplib
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// JoinURL joins a base URL and an endpoint with exactly one slash between
+// them, e.g. "https://api.example.com/v1/" and "/users" give
+// "https://api.example.com/v1/users". an endpoint starting with "?" or "#"
+// is appended as is. base must be an absolute http or https URL without a
+// query or fragment when an endpoint follows, and endpoint must be relative.
+// with an empty base, endpoint is used alone and must be an absolute http or
+// https URL, as FormRequest allowed before BaseURL was validated
+func JoinURL(base, endpoint string) (string, error) {
+	if base == "" {
+		if err := checkAbsolute("endpoint", endpoint); err != nil {
+			return "", err
+		}
+		return endpoint, nil
+	}
+	if err := checkAbsolute("base", base); err != nil {
+		return "", err
+	}
+	u, _ := url.Parse(base)
+	if endpoint == "" {
+		return base, nil
+	}
+	if u.RawQuery != "" || u.Fragment != "" || strings.HasSuffix(base, "?") {
+		return "", fmt.Errorf("base url %q has a query or fragment; put it in the endpoint instead", base)
+	}
+	if e, err := url.Parse(endpoint); err == nil && e.Scheme != "" && e.Host != "" {
+		return "", fmt.Errorf("endpoint %q must be a path, not a full url", endpoint)
+	}
+
+	if endpoint[0] == '?' || endpoint[0] == '#' {
+		if _, err := url.Parse(endpoint); err != nil {
+			return "", fmt.Errorf("endpoint %q: %w", endpoint, err)
+		}
+		return base + endpoint, nil
+	}
+	// doubled slashes would otherwise read as a host: "//users" is "/users"
+	path := "/" + strings.TrimLeft(endpoint, "/")
+	if _, err := url.Parse(path); err != nil {
+		return "", fmt.Errorf("endpoint %q: %w", endpoint, err)
+	}
+	return strings.TrimRight(base, "/") + path, nil
+}
+
+// checkAbsolute fails unless raw is an http or https URL with a host
+func checkAbsolute(what, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%s url %q: %w", what, raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%s url %q needs an http or https scheme", what, raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%s url %q has no host", what, raw)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package httplib
+
+import "testing"
+
+func TestJoinURL(t *testing.T) {
+	tests := []struct {
+		base, endpoint string
+		want           string
+		wantErr        bool
+	}{
+		{base: "https://api.example.com/v1", endpoint: "users", want: "https://api.example.com/v1/users"},
+		{base: "https://api.example.com/v1/", endpoint: "/users", want: "https://api.example.com/v1/users"},
+		{base: "https://api.example.com/v1//", endpoint: "//users", want: "https://api.example.com/v1/users"},
+		{base: "https://api.example.com/v1/", endpoint: "users", want: "https://api.example.com/v1/users"},
+		{base: "https://api.example.com", endpoint: "/users?page=2", want: "https://api.example.com/users?page=2"},
+		{base: "https://api.example.com/v1/", endpoint: "?q=1", want: "https://api.example.com/v1/?q=1"},
+		{base: "https://api.example.com/v1", endpoint: "", want: "https://api.example.com/v1"},
+
+		// no base: the endpoint is the whole URL
+		{base: "", endpoint: "https://host/x", want: "https://host/x"},
+		{base: "", endpoint: "http://host:8080/x?y=1", want: "http://host:8080/x?y=1"},
+		{base: "", endpoint: "/x", wantErr: true},
+		{base: "", endpoint: "ftp://host/x", wantErr: true},
+		{base: "", endpoint: "https:///x", wantErr: true},
+		{base: "", endpoint: "", wantErr: true},
+
+		{base: "api.example.com", endpoint: "/x", wantErr: true},
+		{base: "https://api.example.com?k=v", endpoint: "/x", wantErr: true},
+		{base: "https://api.example.com", endpoint: "https://other.example.com/x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := JoinURL(tt.base, tt.endpoint)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("JoinURL(%q, %q) error = %v, want error %t", tt.base, tt.endpoint, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("JoinURL(%q, %q) = %q, want %q", tt.base, tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+func TestFormRequestAbsoluteEndpoint(t *testing.T) {
+	req, err := FormRequest{Method: "GET", Endpoint: "https://host/x"}.FormRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.URL.String(); got != "https://host/x" {
+		t.Errorf("URL = %q, want https://host/x", got)
+	}
+}
@@ -0,0 +1,242 @@
+// Package jwe encrypts and decrypts HTTP payloads as JSON Web Encryption
+// compact tokens (RFC 7516) for APIs that require encrypted bodies over TLS.
+// supported key management algorithms are dir, A128KW, A256KW, RSA-OAEP and
+// RSA-OAEP-256; supported content encryptions are A128GCM and A256GCM
+package jwe
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// Key management algorithms
+const (
+	Direct      = "dir"
+	A128KW      = "A128KW"
+	A256KW      = "A256KW"
+	RSAOAEP     = "RSA-OAEP"
+	RSAOAEP256  = "RSA-OAEP-256"
+	A128GCM     = "A128GCM"
+	A256GCM     = "A256GCM"
+	ContentType = "application/jose"
+)
+
+// Header is the protected JOSE header of a token
+type Header struct {
+	Alg string `json:"alg"`
+	Enc string `json:"enc"`
+	Kid string `json:"kid,omitempty"`
+	Cty string `json:"cty,omitempty"`
+	Zip string `json:"zip,omitempty"`
+}
+
+// Encrypter produces compact JWE tokens.
+// Key is a []byte shared secret for dir and A*KW, or a *rsa.PublicKey for RSA-OAEP
+type Encrypter struct {
+	Alg   string
+	Enc   string
+	Key   interface{}
+	KeyID string
+}
+
+// Encrypt returns plaintext as a compact JWE token; cty is recorded in the header when set
+func (e Encrypter) Encrypt(plaintext []byte, cty string) (string, error) {
+	keyLen, err := cekLen(e.Enc)
+	if err != nil {
+		return "", err
+	}
+
+	var cek, encryptedKey []byte
+	switch e.Alg {
+	case Direct:
+		secret, ok := e.Key.([]byte)
+		if !ok || len(secret) != keyLen {
+			return "", fmt.Errorf("jwe: %s with %s needs a %d byte key", e.Alg, e.Enc, keyLen)
+		}
+		cek = secret
+	case A128KW, A256KW:
+		kek, err := kwKey(e.Alg, e.Key)
+		if err != nil {
+			return "", err
+		}
+		if cek, err = randomBytes(keyLen); err != nil {
+			return "", err
+		}
+		if encryptedKey, err = wrapKey(kek, cek); err != nil {
+			return "", err
+		}
+	case RSAOAEP, RSAOAEP256:
+		pub, ok := e.Key.(*rsa.PublicKey)
+		if !ok {
+			return "", fmt.Errorf("jwe: %s needs a *rsa.PublicKey", e.Alg)
+		}
+		if cek, err = randomBytes(keyLen); err != nil {
+			return "", err
+		}
+		if encryptedKey, err = rsa.EncryptOAEP(oaepHash(e.Alg), rand.Reader, pub, cek, nil); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("jwe: unsupported alg %q", e.Alg)
+	}
+
+	header, err := json.Marshal(Header{Alg: e.Alg, Enc: e.Enc, Kid: e.KeyID, Cty: cty})
+	if err != nil {
+		return "", err
+	}
+	protected := b64(header)
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+	iv, err := randomBytes(gcm.NonceSize())
+	if err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return strings.Join([]string{protected, b64(encryptedKey), b64(iv), b64(ciphertext), b64(tag)}, "."), nil
+}
+
+// Decrypter opens compact JWE tokens.
+// Key is a []byte shared secret for dir and A*KW, or a *rsa.PrivateKey for RSA-OAEP.
+// tokens using any other alg than Alg are rejected so a peer cannot downgrade the algorithm
+type Decrypter struct {
+	Alg string
+	Key interface{}
+}
+
+// Decrypt returns the plaintext and header of token
+func (d Decrypter) Decrypt(token string) ([]byte, Header, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 5 {
+		return nil, Header{}, errors.New("jwe: token must have 5 parts")
+	}
+	raw := make([][]byte, 5)
+	for i, p := range parts {
+		b, err := base64.RawURLEncoding.DecodeString(p)
+		if err != nil {
+			return nil, Header{}, fmt.Errorf("jwe: part %d: %w", i, err)
+		}
+		raw[i] = b
+	}
+
+	var h Header
+	if err := json.Unmarshal(raw[0], &h); err != nil {
+		return nil, Header{}, fmt.Errorf("jwe: header: %w", err)
+	}
+	if h.Alg != d.Alg {
+		return nil, h, fmt.Errorf("jwe: token alg %q, want %q", h.Alg, d.Alg)
+	}
+	if h.Zip != "" {
+		return nil, h, fmt.Errorf("jwe: compressed payloads (zip %q) are not supported", h.Zip)
+	}
+	keyLen, err := cekLen(h.Enc)
+	if err != nil {
+		return nil, h, err
+	}
+
+	var cek []byte
+	switch h.Alg {
+	case Direct:
+		secret, ok := d.Key.([]byte)
+		if !ok || len(raw[1]) != 0 {
+			return nil, h, errors.New("jwe: dir needs a []byte key and an empty encrypted key")
+		}
+		cek = secret
+	case A128KW, A256KW:
+		kek, err := kwKey(h.Alg, d.Key)
+		if err != nil {
+			return nil, h, err
+		}
+		if cek, err = unwrapKey(kek, raw[1]); err != nil {
+			return nil, h, err
+		}
+	case RSAOAEP, RSAOAEP256:
+		priv, ok := d.Key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, h, fmt.Errorf("jwe: %s needs a *rsa.PrivateKey", h.Alg)
+		}
+		if cek, err = rsa.DecryptOAEP(oaepHash(h.Alg), rand.Reader, priv, raw[1], nil); err != nil {
+			return nil, h, errors.New("jwe: decrypting content key failed")
+		}
+	default:
+		return nil, h, fmt.Errorf("jwe: unsupported alg %q", h.Alg)
+	}
+	if len(cek) != keyLen {
+		return nil, h, fmt.Errorf("jwe: content key is %d bytes, %s needs %d", len(cek), h.Enc, keyLen)
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, h, err
+	}
+	if len(raw[2]) != gcm.NonceSize() {
+		return nil, h, errors.New("jwe: bad iv length")
+	}
+	plaintext, err := gcm.Open(nil, raw[2], append(raw[3], raw[4]...), []byte(parts[0]))
+	if err != nil {
+		return nil, h, errors.New("jwe: authentication failed")
+	}
+	return plaintext, h, nil
+}
+
+func cekLen(enc string) (int, error) {
+	switch enc {
+	case A128GCM:
+		return 16, nil
+	case A256GCM:
+		return 32, nil
+	default:
+		return 0, fmt.Errorf("jwe: unsupported enc %q", enc)
+	}
+}
+
+func kwKey(alg string, key interface{}) ([]byte, error) {
+	kek, ok := key.([]byte)
+	want := 16
+	if alg == A256KW {
+		want = 32
+	}
+	if !ok || len(kek) != want {
+		return nil, fmt.Errorf("jwe: %s needs a %d byte key", alg, want)
+	}
+	return kek, nil
+}
+
+func oaepHash(alg string) hash.Hash {
+	if alg == RSAOAEP {
+		return sha1.New()
+	}
+	return sha256.New()
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
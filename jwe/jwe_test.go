@@ -0,0 +1,168 @@
+package jwe
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// RFC 3394 section 4 test vectors
+func TestKeyWrapRFC3394(t *testing.T) {
+	tests := []struct {
+		name, kek, key, wrapped string
+	}{
+		{
+			"4.1 128 bit key with 128 bit KEK",
+			"000102030405060708090A0B0C0D0E0F",
+			"00112233445566778899AABBCCDDEEFF",
+			"1FA68B0A8112B447 AEF34BD8FB5A7B82 9D3E862371D2CFE5",
+		},
+		{
+			"4.3 128 bit key with 256 bit KEK",
+			"000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+			"00112233445566778899AABBCCDDEEFF",
+			"64E8C3F9CE0F5BA2 63E9777905818A2A 93C8191E7D6E8AE7",
+		},
+		{
+			"4.6 256 bit key with 256 bit KEK",
+			"000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+			"00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F",
+			"28C9F404C4B810F4 CBCCB35CFB87F826 3F5786E2D80ED326 CBC7F0E71A99F43B FB988B9B7A02DD21",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kek, key, want := unhex(t, tt.kek), unhex(t, tt.key), unhex(t, tt.wrapped)
+			got, err := wrapKey(kek, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("wrapKey = %X, want %X", got, want)
+			}
+			unwrapped, err := unwrapKey(kek, want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(unwrapped, key) {
+				t.Fatalf("unwrapKey = %X, want %X", unwrapped, key)
+			}
+
+			tampered := append([]byte(nil), want...)
+			tampered[len(tampered)-1] ^= 1
+			if _, err := unwrapKey(kek, tampered); err == nil {
+				t.Fatal("unwrapKey accepted a tampered key")
+			}
+		})
+	}
+}
+
+// RFC 7516 appendix A.1: the content encryption of the example token, with the
+// content key sent under a freshly generated RSA key in place of the RFC's
+func TestDecryptRFC7516A1(t *testing.T) {
+	const (
+		protected  = "eyJhbGciOiJSU0EtT0FFUCIsImVuYyI6IkEyNTZHQ00ifQ"
+		iv         = "48V1_ALb6US04U3b"
+		ciphertext = "5eym8TW_c8SuK0ltJ3rpYIzOeDQz7TALvtu6UG9oMo4vpzs9tX_EFShS8iB7j6jiSdiwkIr3ajwQzaBtQD_A"
+		tag        = "XFBoMYUZodetZdvTiFvSkQ"
+		plaintext  = "The true sign of intelligence is not knowledge but imagination."
+	)
+	cek := []byte{177, 161, 244, 128, 84, 143, 225, 115, 63, 180, 3, 255, 107, 154, 212, 246,
+		138, 7, 110, 91, 112, 46, 34, 105, 47, 130, 203, 46, 122, 234, 64, 252}
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedKey, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, &priv.PublicKey, cek, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := strings.Join([]string{protected, b64(encryptedKey), iv, ciphertext, tag}, ".")
+
+	got, h, err := Decrypter{Alg: RSAOAEP, Key: priv}.Decrypt(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != plaintext {
+		t.Errorf("plaintext = %q, want %q", got, plaintext)
+	}
+	if h.Alg != RSAOAEP || h.Enc != A256GCM {
+		t.Errorf("header = %+v", h)
+	}
+
+	// the protected header is authenticated data; changing it must fail
+	forged := strings.Replace(token, protected, b64([]byte(`{"alg":"RSA-OAEP","enc":"A256GCM","kid":"x"}`)), 1)
+	if _, _, err := (Decrypter{Alg: RSAOAEP, Key: priv}).Decrypt(forged); err == nil {
+		t.Error("Decrypt accepted a token with a modified protected header")
+	}
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key128, key256 := bytes.Repeat([]byte{1}, 16), bytes.Repeat([]byte{2}, 32)
+	tests := []struct {
+		alg, enc string
+		enckey   interface{}
+		deckey   interface{}
+	}{
+		{Direct, A128GCM, key128, key128},
+		{Direct, A256GCM, key256, key256},
+		{A128KW, A128GCM, key128, key128},
+		{A128KW, A256GCM, key128, key128},
+		{A256KW, A256GCM, key256, key256},
+		{RSAOAEP, A256GCM, &priv.PublicKey, priv},
+		{RSAOAEP256, A128GCM, &priv.PublicKey, priv},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alg+"/"+tt.enc, func(t *testing.T) {
+			token, err := Encrypter{Alg: tt.alg, Enc: tt.enc, Key: tt.enckey, KeyID: "k1"}.Encrypt([]byte(`{"ssn":"123"}`), "application/json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := strings.Count(token, "."); n != 4 {
+				t.Fatalf("token has %d dots, want 4", n)
+			}
+			got, h, err := Decrypter{Alg: tt.alg, Key: tt.deckey}.Decrypt(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != `{"ssn":"123"}` || h.Kid != "k1" || h.Cty != "application/json" || h.Enc != tt.enc {
+				t.Errorf("got %q with header %+v", got, h)
+			}
+
+			parts := strings.Split(token, ".")
+			parts[3] = b64(append([]byte{0}, []byte(parts[3])...))
+			if _, _, err := (Decrypter{Alg: tt.alg, Key: tt.deckey}).Decrypt(strings.Join(parts, ".")); err == nil {
+				t.Error("Decrypt accepted a tampered ciphertext")
+			}
+		})
+	}
+}
+
+func TestDecryptRejectsOtherAlg(t *testing.T) {
+	key := bytes.Repeat([]byte{3}, 16)
+	token, err := Encrypter{Alg: Direct, Enc: A128GCM, Key: key}.Encrypt([]byte("x"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := (Decrypter{Alg: A128KW, Key: key}).Decrypt(token); err == nil {
+		t.Error("Decrypt accepted a token with another alg")
+	}
+}
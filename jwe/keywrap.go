@@ -0,0 +1,84 @@
+package jwe
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+// defaultIV is the RFC 3394 initial value
+var defaultIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// wrapKey implements AES Key Wrap (RFC 3394) of cek under kek
+func wrapKey(kek, cek []byte) ([]byte, error) {
+	if len(cek)%8 != 0 || len(cek) < 16 {
+		return nil, errors.New("jwe: key to wrap must be a multiple of 8 bytes")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(cek) / 8
+	r := make([][]byte, n)
+	for i := range r {
+		r[i] = append([]byte(nil), cek[i*8:(i+1)*8]...)
+	}
+	a := append([]byte(nil), defaultIV...)
+	buf := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 0; i < n; i++ {
+			copy(buf, a)
+			copy(buf[8:], r[i])
+			block.Encrypt(buf, buf)
+			t := uint64(n*j + i + 1)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(buf[:8])^t)
+			copy(r[i], buf[8:])
+		}
+	}
+
+	out := append([]byte(nil), a...)
+	for _, ri := range r {
+		out = append(out, ri...)
+	}
+	return out, nil
+}
+
+// unwrapKey reverses wrapKey and verifies the integrity check value
+func unwrapKey(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 24 {
+		return nil, errors.New("jwe: wrapped key has invalid length")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(wrapped)/8 - 1
+	a := append([]byte(nil), wrapped[:8]...)
+	r := make([][]byte, n)
+	for i := range r {
+		r[i] = append([]byte(nil), wrapped[(i+1)*8:(i+2)*8]...)
+	}
+	buf := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n - 1; i >= 0; i-- {
+			t := uint64(n*j + i + 1)
+			binary.BigEndian.PutUint64(buf, binary.BigEndian.Uint64(a)^t)
+			copy(buf[8:], r[i])
+			block.Decrypt(buf, buf)
+			copy(a, buf[:8])
+			copy(r[i], buf[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(a, defaultIV) != 1 {
+		return nil, errors.New("jwe: key unwrap integrity check failed")
+	}
+
+	out := make([]byte, 0, n*8)
+	for _, ri := range r {
+		out = append(out, ri...)
+	}
+	return out, nil
+}
@@ -0,0 +1,84 @@
+package jwe
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// Transport encrypts request bodies with Encrypter and decrypts application/jose
+// responses with Decrypter; either may be nil to only handle one direction.
+// it plugs into httplib.NewClient.Transport. a nil Next uses http.DefaultTransport
+type Transport struct {
+	Next      http.RoundTripper
+	Encrypter *Encrypter
+	Decrypter *Decrypter
+}
+
+// RoundTrip encrypts the outgoing body and decrypts the response body
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if t.Encrypter != nil && req.Body != nil && req.Body != http.NoBody {
+		plaintext, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		token, err := t.Encrypter.Encrypt(plaintext, req.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, err
+		}
+
+		r := req.Clone(req.Context())
+		r.Body = io.NopCloser(bytes.NewReader([]byte(token)))
+		r.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader([]byte(token))), nil
+		}
+		r.ContentLength = int64(len(token))
+		r.Header.Set("Content-Type", ContentType)
+		req = r
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil || t.Decrypter == nil || !isJOSE(resp.Header) {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := t.Decrypter.Transform(body, resp.Header)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(plaintext))
+	resp.ContentLength = int64(len(plaintext))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(plaintext)))
+	return resp, nil
+}
+
+// Transform decrypts a token body and restores the payload Content-Type from cty.
+// its signature matches httplib.Transformer so it can be used in a transformer chain
+func (d Decrypter) Transform(body []byte, header http.Header) ([]byte, error) {
+	plaintext, h, err := d.Decrypt(string(body))
+	if err != nil {
+		return nil, err
+	}
+	if h.Cty != "" {
+		header.Set("Content-Type", h.Cty)
+	}
+	return plaintext, nil
+}
+
+func isJOSE(h http.Header) bool {
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	return mediaType == ContentType
+}
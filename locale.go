@@ -0,0 +1,61 @@
+package httplib
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// AcceptLanguage formats locales in order of preference as an Accept-Language
+// value, lowering the quality by 0.1 per position down to 0.1, e.g.
+// AcceptLanguage("de-DE", "de", "en") is "de-DE, de;q=0.9, en;q=0.8"
+func AcceptLanguage(locales ...string) string {
+	parts := make([]string, 0, len(locales))
+	for _, l := range locales {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+		if len(parts) == 0 {
+			parts = append(parts, l)
+			continue
+		}
+		q := 10 - len(parts)
+		if q < 1 {
+			q = 1
+		}
+		parts = append(parts, fmt.Sprintf("%s;q=0.%d", l, q))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// WithLocale returns a copy of req asking for locales in order of preference
+// through Accept-Language, replacing any set before
+func WithLocale(req *http.Request, locales ...string) *http.Request {
+	r := req.Clone(req.Context())
+	if v := AcceptLanguage(locales...); v != "" {
+		r.Header.Set("Accept-Language", v)
+	} else {
+		r.Header.Del("Accept-Language")
+	}
+	return r
+}
+
+// ContentLanguage returns the languages a response says its content is in,
+// from its Content-Language header, e.g. ["de-DE"]
+func ContentLanguage(h http.Header) []string {
+	var langs []string
+	for _, v := range h.Values("Content-Language") {
+		for _, l := range strings.Split(v, ",") {
+			if l = strings.TrimSpace(l); l != "" {
+				langs = append(langs, l)
+			}
+		}
+	}
+	return langs
+}
+
+// ContentLanguage returns the languages of the response content
+func (r *Response) ContentLanguage() []string {
+	return ContentLanguage(r.Header)
+}
@@ -0,0 +1,81 @@
+package httplib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/clairmont32/httplib/internal/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// Logger receives the package's log output as a message with alternating
+// keys and values. *slog.Logger satisfies it; wrap other loggers in a few lines
+type Logger = logging.Logger
+
+// SetLogger sends the log output of every httplib package to l. nothing is
+// logged until it is called; nil discards logs again. a client's own Logger
+// takes precedence for its requests
+func SetLogger(l Logger) {
+	logging.Set(l)
+}
+
+// NewLogrusLogger adapts a logrus logger, e.g. logrus.StandardLogger(), for
+// SetLogger. keys become logrus fields
+func NewLogrusLogger(l logrus.FieldLogger) Logger {
+	return logrusLogger{l: l}
+}
+
+type logrusLogger struct {
+	l logrus.FieldLogger
+}
+
+func (l logrusLogger) Debug(msg string, kv ...interface{}) { l.with(kv).Debug(msg) }
+func (l logrusLogger) Info(msg string, kv ...interface{})  { l.with(kv).Info(msg) }
+func (l logrusLogger) Warn(msg string, kv ...interface{})  { l.with(kv).Warn(msg) }
+func (l logrusLogger) Error(msg string, kv ...interface{}) { l.with(kv).Error(msg) }
+
+func (l logrusLogger) with(kv []interface{}) logrus.FieldLogger {
+	if len(kv) == 0 {
+		return l.l
+	}
+	fields := make(logrus.Fields, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		if i+1 < len(kv) {
+			fields[key] = kv[i+1]
+		} else {
+			fields["!BADKEY"] = kv[i]
+		}
+	}
+	return l.l.WithFields(fields)
+}
+
+// logger returns the client's Logger, or the package wide one
+func (c NewClient) logger() Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return logging.Get()
+}
+
+// withLogger returns req carrying the client's Logger, so transports it is
+// sent through log where the client does
+func (c NewClient) withLogger(req *http.Request) *http.Request {
+	if c.Logger == nil {
+		return req
+	}
+	return req.WithContext(context.WithValue(req.Context(), loggerKey, c.Logger))
+}
+
+// requestLogger returns the Logger of the client req is sent with, or the
+// package wide one
+func requestLogger(req *http.Request) Logger {
+	if l, ok := req.Context().Value(loggerKey).(Logger); ok {
+		return l
+	}
+	return logging.Get()
+}
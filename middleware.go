@@ -0,0 +1,119 @@
+package httplib
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// Middleware wraps a transport with cross-cutting behavior such as auth,
+// logging or metrics. it runs once per attempt, inside retries and redirects
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// Chain wraps rt in mws so the first middleware sees each request first.
+// a nil rt stands for http.DefaultTransport
+func Chain(rt http.RoundTripper, mws ...Middleware) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	for i := len(mws) - 1; i >= 0; i-- {
+		rt = mws[i](rt)
+	}
+	return rt
+}
+
+// middlewareChain keeps the transport built from a client's Middleware so the
+// factories run once per client rather than once per request
+type middlewareChain struct {
+	mu   sync.Mutex
+	base http.RoundTripper
+	rt   http.RoundTripper
+}
+
+// middlewareKey identifies a Middleware slice by its backing array, which
+// every copy of a client shares, literal or built by NewClientWithOptions
+type middlewareKey struct {
+	first *Middleware
+	n     int
+}
+
+// maxChains bounds the chain cache for programs that create short-lived
+// clients; when it is full the cache starts over and chains are rebuilt
+const maxChains = 1024
+
+var (
+	chainsMu sync.Mutex
+	chains   = make(map[middlewareKey]*middlewareChain)
+)
+
+func chainFor(mws []Middleware) *middlewareChain {
+	key := middlewareKey{first: &mws[0], n: len(mws)}
+	chainsMu.Lock()
+	defer chainsMu.Unlock()
+	m, ok := chains[key]
+	if !ok {
+		if len(chains) >= maxChains {
+			chains = make(map[middlewareKey]*middlewareChain)
+		}
+		m = &middlewareChain{}
+		chains[key] = m
+	}
+	return m
+}
+
+// chain returns base wrapped in the client Middleware, built on first use and
+// reused while the client keeps the same base and Middleware slice. a panic
+// in a factory is returned as a *PanicError
+func (c NewClient) chain(base http.RoundTripper) (http.RoundTripper, error) {
+	m := chainFor(c.Middleware)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.rt != nil && sameTransport(m.base, base) {
+		return m.rt, nil
+	}
+	rt, err := buildChain(base, c.Middleware)
+	if err != nil {
+		return nil, err
+	}
+	m.base, m.rt = base, rt
+	return rt, nil
+}
+
+func buildChain(base http.RoundTripper, mws []Middleware) (rt http.RoundTripper, err error) {
+	defer recoverInto(&err)
+	return Chain(base, mws...), nil
+}
+
+// sameTransport compares transports without panicking on values that are
+// not comparable, which are never considered the same
+func sameTransport(a, b http.RoundTripper) (same bool) {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	if a == nil {
+		return true
+	}
+	if !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	// structs can be comparable by type and still hold uncomparable values
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
+
+// buildMiddleware builds the chain of a client made by NewClientWithOptions
+// up front, so a panicking factory is reported there
+func (c *NewClient) buildMiddleware() error {
+	if len(c.Middleware) == 0 {
+		return nil
+	}
+	if _, err := c.chain(c.baseTransport()); err != nil {
+		return fmt.Errorf("building middleware: %w", err)
+	}
+	return nil
+}
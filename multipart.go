@@ -0,0 +1,152 @@
+package httplib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"sync"
+
+	"github.com/clairmont32/httplib/internal/logging"
+)
+
+// MultipartRequest builds a multipart/form-data request whose body is streamed
+// from the file readers while it is sent, so uploads of any size use constant
+// memory. the body can be read once, so such requests are not retried or
+// redirected with their body; wrap it with UploadProgress to follow the upload
+type MultipartRequest struct {
+	BaseURL  string
+	Endpoint string
+	// Method defaults to POST
+	Method string
+
+	// Fields are written first, in order
+	Fields []MultipartField
+	// Files are written after the fields, in order
+	Files []MultipartFile
+}
+
+// MultipartField is a plain form field
+type MultipartField struct {
+	Name  string
+	Value string
+}
+
+// MultipartFile is a file part read from Reader while the request is sent.
+// Reader is closed afterwards when it is an io.Closer
+type MultipartFile struct {
+	Field    string
+	FileName string
+	// ContentType defaults to application/octet-stream
+	ContentType string
+	Reader      io.Reader
+}
+
+// FormRequest creates the streaming HTTP request
+func (r MultipartRequest) FormRequest() (*http.Request, error) {
+	return r.FormRequestWithContext(context.Background())
+}
+
+// FormRequestWithContext creates the streaming HTTP request bound to ctx
+func (r MultipartRequest) FormRequestWithContext(ctx context.Context) (*http.Request, error) {
+	for i, f := range r.Files {
+		if f.Reader == nil {
+			return nil, fmt.Errorf("multipart file %d (%s) has no reader", i, f.Field)
+		}
+	}
+	method := r.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	u, err := JoinURL(r.BaseURL, r.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	body := &multipartBody{r: r}
+	body.pr, body.pw = io.Pipe()
+	body.mw = multipart.NewWriter(body.pw)
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		logging.Get().Debug("error forming HTTP request", "error", redactErr(err))
+		return nil, err
+	}
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", body.mw.FormDataContentType())
+	return req, nil
+}
+
+// multipartBody starts encoding on the first read, so requests that are never
+// sent hold no goroutine and leave their files to the caller
+type multipartBody struct {
+	r      MultipartRequest
+	pr     *io.PipeReader
+	pw     *io.PipeWriter
+	mw     *multipart.Writer
+	once   sync.Once
+	closed sync.Once
+}
+
+func (b *multipartBody) Read(p []byte) (int, error) {
+	b.once.Do(func() {
+		go func() {
+			// a failed write closes the pipe with the error, which the
+			// transport reports as the error of the request
+			b.pw.CloseWithError(b.r.write(b.mw))
+		}()
+	})
+	return b.pr.Read(p)
+}
+
+// Close stops the encoding; the writer sees a closed pipe and returns
+func (b *multipartBody) Close() error {
+	b.once.Do(func() { b.r.closeFiles() })
+	b.closed.Do(func() { _ = b.pr.Close() })
+	return nil
+}
+
+// write encodes every part into mw and closes it; it returns early when the
+// transport stopped reading the body
+func (r MultipartRequest) write(mw *multipart.Writer) error {
+	defer r.closeFiles()
+	for _, f := range r.Fields {
+		if err := mw.WriteField(f.Name, f.Value); err != nil {
+			return err
+		}
+	}
+	for _, f := range r.Files {
+		ct := f.ContentType
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(f.Field), escapeQuotes(f.FileName)))
+		h.Set("Content-Type", ct)
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, f.Reader); err != nil {
+			return fmt.Errorf("multipart file %s: %w", f.Field, err)
+		}
+	}
+	return mw.Close()
+}
+
+func (r MultipartRequest) closeFiles() {
+	for _, f := range r.Files {
+		if c, ok := f.Reader.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// escapeQuotes escapes a Content-Disposition parameter like mime/multipart does
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
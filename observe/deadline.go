@@ -0,0 +1,183 @@
+package observe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultDeadlineSamples is how many recent durations DeadlineStats keeps per endpoint
+const defaultDeadlineSamples = 1000
+
+// DeadlineStats tracks how close requests come to their timeouts per endpoint
+// so timeouts can be tuned with evidence. register it with bus.Handle(stats.Observe)
+type DeadlineStats struct {
+	// MaxSamples caps the recent durations kept per endpoint; zero keeps 1000
+	MaxSamples int
+
+	mu        sync.Mutex
+	endpoints map[string]*deadlineSamples
+}
+
+// deadlineSamples is a ring of recent durations for one endpoint
+type deadlineSamples struct {
+	durations []time.Duration
+	next      int
+	count     int
+	timedOut  int
+	timeout   time.Duration
+}
+
+// Observe records RequestFinished events and ignores everything else
+func (s *DeadlineStats) Observe(e Event) {
+	f, ok := e.(RequestFinished)
+	if !ok {
+		return
+	}
+	key := endpointKey(f.Method, f.URL)
+	max := s.MaxSamples
+	if max <= 0 {
+		max = defaultDeadlineSamples
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.endpoints == nil {
+		s.endpoints = make(map[string]*deadlineSamples)
+	}
+	d := s.endpoints[key]
+	if d == nil {
+		d = &deadlineSamples{}
+		s.endpoints[key] = d
+	}
+	if len(d.durations) < max {
+		d.durations = append(d.durations, f.Duration)
+	} else {
+		d.durations[d.next%len(d.durations)] = f.Duration
+	}
+	d.next++
+	d.count++
+	d.timeout = f.Timeout
+	if isTimeout(f.Err) {
+		d.timedOut++
+	}
+}
+
+// DeadlineReport summarizes one endpoint; percentiles cover the recent samples
+type DeadlineReport struct {
+	Endpoint string
+	Count    int
+	TimedOut int
+	Timeout  time.Duration
+	P50      time.Duration
+	P90      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+}
+
+// Report returns a summary per endpoint, closest to its timeout first
+func (s *DeadlineStats) Report() []DeadlineReport {
+	s.mu.Lock()
+	reports := make([]DeadlineReport, 0, len(s.endpoints))
+	for key, d := range s.endpoints {
+		sorted := append([]time.Duration(nil), d.durations...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		reports = append(reports, DeadlineReport{
+			Endpoint: key,
+			Count:    d.count,
+			TimedOut: d.timedOut,
+			Timeout:  d.timeout,
+			P50:      percentile(sorted, 0.50),
+			P90:      percentile(sorted, 0.90),
+			P99:      percentile(sorted, 0.99),
+			Max:      sorted[len(sorted)-1],
+		})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(reports, func(i, j int) bool {
+		ri, rj := reports[i].headroom(), reports[j].headroom()
+		if ri != rj {
+			return ri > rj
+		}
+		return reports[i].Endpoint < reports[j].Endpoint
+	})
+	return reports
+}
+
+// String reads e.g. "GET /v1/export: p99 is 9.4s of your 10s timeout (1 of 250 timed out)"
+func (r DeadlineReport) String() string {
+	if r.Timeout <= 0 {
+		return fmt.Sprintf("%s: p99 is %s with no timeout", r.Endpoint, round(r.P99))
+	}
+	return fmt.Sprintf("%s: p99 is %s of your %s timeout (%d of %d timed out)",
+		r.Endpoint, round(r.P99), round(r.Timeout), r.TimedOut, r.Count)
+}
+
+// Advice suggests a timeout change, or returns "" when the timeout looks right.
+// a p99 above 80% of the timeout or any timeouts suggest raising it; a p99
+// under 10% suggests lowering it to a few times the p99
+func (r DeadlineReport) Advice() string {
+	switch {
+	case r.Timeout <= 0:
+		return fmt.Sprintf("%s: no timeout set; consider %s", r.Endpoint, round(3*r.P99))
+	case r.TimedOut > 0 || r.headroom() > 0.8:
+		return fmt.Sprintf("%s: raise the timeout above %s or speed up the endpoint", r.Endpoint, round(r.Max))
+	case r.headroom() < 0.1 && r.Count >= 100:
+		return fmt.Sprintf("%s: timeout could be lowered to about %s", r.Endpoint, round(3*r.P99))
+	default:
+		return ""
+	}
+}
+
+// headroom is the p99 as a fraction of the timeout
+func (r DeadlineReport) headroom() float64 {
+	if r.Timeout <= 0 {
+		return 0
+	}
+	return float64(r.P99) / float64(r.Timeout)
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted))*p+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// endpointKey groups requests by method, host and path, ignoring the query
+func endpointKey(method, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return method + " " + rawURL
+	}
+	return method + " " + u.Host + u.Path
+}
+
+func isTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	var ne net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout())
+}
+
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(100 * time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(time.Millisecond)
+	default:
+		return d
+	}
+}
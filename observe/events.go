@@ -0,0 +1,198 @@
+// Package observe defines the typed events httplib clients publish and the
+// EventBus that fans them out. it has no dependency on the client so metrics,
+// tracing and UI integrations can build on it without import cycles
+package observe
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/clairmont32/httplib/internal/logging"
+)
+
+// Event is a typed notification of client activity published on an EventBus.
+// switch on the concrete type to handle the events of interest
+type Event interface {
+	EventTime() time.Time
+}
+
+// RequestStarted is published before a request is sent
+type RequestStarted struct {
+	Time   time.Time
+	Method string
+	URL    string
+}
+
+// RequestFinished is published once a request returned a response or failed
+type RequestFinished struct {
+	Time     time.Time
+	Method   string
+	URL      string
+	Status   int
+	Duration time.Duration
+	Err      error
+
+	// Timeout is the time the request was allowed, from the client timeout or
+	// the context deadline whichever is sooner; zero means unlimited
+	Timeout time.Duration
+
+	// ServerTiming holds the upstream's own timings from its Server-Timing header
+	ServerTiming []ServerTiming
+}
+
+// RetryScheduled is published when a failed attempt will be retried after Delay
+type RetryScheduled struct {
+	Time    time.Time
+	Method  string
+	URL     string
+	Attempt int
+	Delay   time.Duration
+	Status  int
+	Err     error
+}
+
+// BudgetExceeded is published when a request was cancelled for running past its
+// latency budget, separately from the RequestFinished of the cancelled request
+type BudgetExceeded struct {
+	Time    time.Time
+	Method  string
+	URL     string
+	Budget  time.Duration
+	Elapsed time.Duration
+}
+
+// BreakerOpened is published when a circuit breaker starts fast-failing requests to Host
+type BreakerOpened struct {
+	Time time.Time
+	Host string
+}
+
+// Deprecated is published for every response announcing through its
+// Deprecation or Sunset header that the endpoint is going away
+type Deprecated struct {
+	Time   time.Time
+	Method string
+	// URL is the request URL without its query
+	URL string
+	// Date is when the endpoint was or will be deprecated; zero when not given
+	Date time.Time
+	// Sunset is when the endpoint stops working; zero when not announced
+	Sunset time.Time
+	Link   string
+}
+
+// EventTime returns when the event happened
+func (e RequestStarted) EventTime() time.Time { return e.Time }
+
+// EventTime returns when the event happened
+func (e RequestFinished) EventTime() time.Time { return e.Time }
+
+// EventTime returns when the event happened
+func (e RetryScheduled) EventTime() time.Time { return e.Time }
+
+// EventTime returns when the event happened
+func (e BudgetExceeded) EventTime() time.Time { return e.Time }
+
+// EventTime returns when the event happened
+func (e BreakerOpened) EventTime() time.Time { return e.Time }
+
+// EventTime returns when the event happened
+func (e Deprecated) EventTime() time.Time { return e.Time }
+
+// EventBus fans client events out to channel subscribers and callbacks.
+// the zero value is ready to use; publishing never blocks on a slow subscriber
+type EventBus struct {
+	dropped uint64 // first for 64-bit atomic alignment on 32-bit platforms
+
+	mu       sync.RWMutex
+	nextID   int
+	channels map[int]chan Event
+	handlers map[int]func(Event)
+}
+
+// Subscribe returns a channel receiving every event published from now on and
+// a function that unsubscribes and closes it. events are dropped for this
+// subscriber while its buffer is full
+func (b *EventBus) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.channels == nil {
+		b.channels = make(map[int]chan Event)
+	}
+	id := b.nextID
+	b.nextID++
+	b.channels[id] = ch
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.channels, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Handle calls fn synchronously for every event published from now on
+// and returns a function removing it. fn runs on the request path so keep it fast
+func (b *EventBus) Handle(fn func(Event)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.handlers == nil {
+		b.handlers = make(map[int]func(Event))
+	}
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = fn
+	return func() {
+		b.mu.Lock()
+		delete(b.handlers, id)
+		b.mu.Unlock()
+	}
+}
+
+// Publish delivers e to every subscriber. it is a no-op on a nil bus
+func (b *EventBus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	for _, ch := range b.channels {
+		select {
+		case ch <- e:
+		default:
+			atomic.AddUint64(&b.dropped, 1)
+		}
+	}
+	handlers := make([]func(Event), 0, len(b.handlers))
+	for _, fn := range b.handlers {
+		handlers = append(handlers, fn)
+	}
+	b.mu.RUnlock()
+
+	// run callbacks without the lock so they may subscribe or unsubscribe
+	for _, fn := range handlers {
+		callHandler(fn, e)
+	}
+}
+
+// callHandler runs fn and logs instead of propagating a panic, so a buggy
+// handler cannot crash the request that published e
+func callHandler(fn func(Event), e Event) {
+	defer func() {
+		if v := recover(); v != nil {
+			logging.Get().Error("event handler panicked", "event", fmt.Sprintf("%T", e), "panic", v, "stack", string(debug.Stack()))
+		}
+	}()
+	fn(e)
+}
+
+// Dropped returns how many events were discarded because a subscriber fell behind
+func (b *EventBus) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
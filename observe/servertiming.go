@@ -0,0 +1,70 @@
+package observe
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerTiming is one metric of a Server-Timing header, e.g. db;dur=53.2;desc="query"
+type ServerTiming struct {
+	Name        string
+	Duration    time.Duration
+	Description string
+}
+
+// ParseServerTiming parses Server-Timing header values. malformed parameters
+// are skipped rather than failing the whole header
+func ParseServerTiming(values []string) []ServerTiming {
+	var out []ServerTiming
+	for _, v := range values {
+		for _, entry := range splitQuoted(v, ',') {
+			params := splitQuoted(entry, ';')
+			name := strings.TrimSpace(params[0])
+			if name == "" {
+				continue
+			}
+			st := ServerTiming{Name: name}
+			for _, p := range params[1:] {
+				key, val, ok := strings.Cut(strings.TrimSpace(p), "=")
+				if !ok {
+					continue
+				}
+				val = strings.TrimSpace(val)
+				switch strings.ToLower(strings.TrimSpace(key)) {
+				case "dur":
+					if ms, err := strconv.ParseFloat(val, 64); err == nil {
+						st.Duration = time.Duration(ms * float64(time.Millisecond))
+					}
+				case "desc":
+					if unq, err := strconv.Unquote(val); err == nil {
+						val = unq
+					}
+					st.Description = val
+				}
+			}
+			out = append(out, st)
+		}
+	}
+	return out
+}
+
+// splitQuoted splits s on sep outside double quoted strings
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, escaped, start := false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case escaped:
+			escaped = false
+		case c == '\\' && quoted:
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
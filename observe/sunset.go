@@ -0,0 +1,110 @@
+package observe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SunsetTracker collects the deprecation and sunset notices seen per endpoint
+// so upcoming API removals can be reported ahead of time. register it with
+// bus.Handle(tracker.Observe)
+type SunsetTracker struct {
+	mu        sync.Mutex
+	endpoints map[string]*SunsetNotice
+}
+
+// SunsetNotice is the latest deprecation announced by one endpoint
+type SunsetNotice struct {
+	Endpoint   string
+	Deprecated time.Time
+	// Sunset is when the endpoint stops working; zero when only deprecated
+	Sunset    time.Time
+	Link      string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Count     int
+}
+
+// Observe records Deprecated events and ignores everything else
+func (s *SunsetTracker) Observe(e Event) {
+	d, ok := e.(Deprecated)
+	if !ok {
+		return
+	}
+	key := endpointKey(d.Method, d.URL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.endpoints == nil {
+		s.endpoints = make(map[string]*SunsetNotice)
+	}
+	n := s.endpoints[key]
+	if n == nil {
+		n = &SunsetNotice{Endpoint: key, FirstSeen: d.Time}
+		s.endpoints[key] = n
+	}
+	n.Count++
+	n.LastSeen = d.Time
+	// a vendor may move the dates, so the latest announcement wins
+	n.Deprecated, n.Sunset = d.Date, d.Sunset
+	if d.Link != "" {
+		n.Link = d.Link
+	}
+}
+
+// Report returns every endpoint seen deprecated, soonest sunset first and
+// endpoints without a sunset date last
+func (s *SunsetTracker) Report() []SunsetNotice {
+	s.mu.Lock()
+	notices := make([]SunsetNotice, 0, len(s.endpoints))
+	for _, n := range s.endpoints {
+		notices = append(notices, *n)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(notices, func(i, j int) bool {
+		si, sj := notices[i].Sunset, notices[j].Sunset
+		if si.IsZero() != sj.IsZero() {
+			return sj.IsZero()
+		}
+		if !si.Equal(sj) {
+			return si.Before(sj)
+		}
+		return notices[i].Endpoint < notices[j].Endpoint
+	})
+	return notices
+}
+
+// Upcoming returns the endpoints whose sunset is within d from now, including
+// ones already past it, soonest first
+func (s *SunsetTracker) Upcoming(d time.Duration) []SunsetNotice {
+	limit := time.Now().Add(d)
+	var upcoming []SunsetNotice
+	for _, n := range s.Report() {
+		if n.Sunset.IsZero() || n.Sunset.After(limit) {
+			break
+		}
+		upcoming = append(upcoming, n)
+	}
+	return upcoming
+}
+
+// String reads e.g. "GET api.example.com/v1/users: deprecated since 2024-01-01, sunset on 2024-06-30, see https://..."
+func (n SunsetNotice) String() string {
+	var b strings.Builder
+	b.WriteString(n.Endpoint)
+	b.WriteString(": deprecated")
+	if !n.Deprecated.IsZero() {
+		fmt.Fprintf(&b, " since %s", n.Deprecated.UTC().Format("2006-01-02"))
+	}
+	if !n.Sunset.IsZero() {
+		fmt.Fprintf(&b, ", sunset on %s", n.Sunset.UTC().Format("2006-01-02"))
+	}
+	if n.Link != "" {
+		fmt.Fprintf(&b, ", see %s", n.Link)
+	}
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package httplib
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clairmont32/httplib/observe"
+	"github.com/clairmont32/httplib/retry"
+)
+
+// OperationalHeaders are the common operational response headers parsed into
+// typed values, so callers stop re-parsing them ad hoc
+type OperationalHeaders struct {
+	// RetryAfter is the wait asked for by Retry-After when HasRetryAfter is set
+	RetryAfter    time.Duration
+	HasRetryAfter bool
+
+	RateLimit RateLimitInfo
+
+	// ServerTiming holds the metrics of Server-Timing in header order
+	ServerTiming []ServerTiming
+
+	// Cache is the raw X-Cache value; CacheHit reports whether it starts with HIT
+	Cache    string
+	CacheHit bool
+}
+
+// RateLimitInfo is read from X-RateLimit-Limit, -Remaining and -Reset, or the
+// RateLimit-* fields without the X- prefix. numbers are -1 when absent
+type RateLimitInfo struct {
+	Limit     int
+	Remaining int
+	// Reset is when the window resets; zero when absent
+	Reset time.Time
+}
+
+// ServerTiming is one metric of a Server-Timing header
+type ServerTiming = observe.ServerTiming
+
+// resetEpochFloor tells epoch timestamps from delta seconds in rate limit reset headers
+const resetEpochFloor = 1_000_000_000
+
+// ParseOperationalHeaders parses h as received at now
+func ParseOperationalHeaders(h http.Header, now time.Time) OperationalHeaders {
+	var o OperationalHeaders
+	o.RetryAfter, o.HasRetryAfter = retry.ParseRetryAfter(h, now)
+
+	o.RateLimit = RateLimitInfo{
+		Limit:     headerInt(h, "X-RateLimit-Limit", "RateLimit-Limit"),
+		Remaining: headerInt(h, "X-RateLimit-Remaining", "RateLimit-Remaining"),
+	}
+	if reset := headerInt(h, "X-RateLimit-Reset", "RateLimit-Reset"); reset >= 0 {
+		if reset >= resetEpochFloor {
+			o.RateLimit.Reset = time.Unix(int64(reset), 0)
+		} else {
+			o.RateLimit.Reset = now.Add(time.Duration(reset) * time.Second)
+		}
+	}
+
+	o.ServerTiming = observe.ParseServerTiming(h.Values("Server-Timing"))
+	o.Cache = h.Get("X-Cache")
+	o.CacheHit = strings.HasPrefix(strings.ToUpper(strings.TrimSpace(o.Cache)), "HIT")
+	return o
+}
+
+// headerInt returns the first of keys that holds an integer, or -1
+func headerInt(h http.Header, keys ...string) int {
+	for _, k := range keys {
+		if v := strings.TrimSpace(h.Get(k)); v != "" {
+			if n, err := strconv.Atoi(v); err == nil {
+				return n
+			}
+		}
+	}
+	return -1
+}
+
+// formatServerTiming renders metrics as "db=53.2ms cache=1ms" for logs
+func formatServerTiming(metrics []ServerTiming) string {
+	parts := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		parts = append(parts, m.Name+"="+m.Duration.String())
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,227 @@
+package httplib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/clairmont32/httplib/observe"
+	"github.com/clairmont32/httplib/ratelimit"
+	"github.com/clairmont32/httplib/retry"
+)
+
+// Option configures the client built by NewClientWithOptions
+type Option func(*clientConfig)
+
+// clientConfig collects the client under construction and the first option error
+type clientConfig struct {
+	client NewClient
+	strict bool
+	err    error
+}
+
+// NewClientWithOptions returns a client with a 10s timeout configured by opts.
+// it is the stable way to configure a client as NewClient grows fields
+//
+//	c, err := NewClientWithOptions(WithTimeout(5*time.Second), WithRetry(retry.Default()))
+func NewClientWithOptions(opts ...Option) (*NewClient, error) {
+	cfg := &clientConfig{client: NewClient{Timeout: 10 * time.Second}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.err != nil {
+		return nil, cfg.err
+	}
+	if cfg.strict {
+		if err := cfg.client.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.client.buildMiddleware(); err != nil {
+		return nil, err
+	}
+	return &cfg.client, nil
+}
+
+// WithTimeout sets the time limit for each request; zero means no limit
+func WithTimeout(d time.Duration) Option {
+	return func(c *clientConfig) {
+		c.client.Timeout = d
+	}
+}
+
+// WithTransport sets the transport requests are sent through
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *clientConfig) {
+		c.client.Transport = rt
+	}
+}
+
+// WithProxy sends requests through the proxy at proxyURL. it needs the
+// transport to be nil or an *http.Transport, which is cloned rather than modified
+func WithProxy(proxyURL string) Option {
+	return func(c *clientConfig) {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			c.fail(fmt.Errorf("proxy url: %w", err))
+			return
+		}
+		var t *http.Transport
+		switch base := c.client.Transport.(type) {
+		case nil:
+			t = http.DefaultTransport.(*http.Transport).Clone()
+		case *http.Transport:
+			t = base.Clone()
+		default:
+			c.fail(fmt.Errorf("WithProxy needs an *http.Transport, got %T", base))
+			return
+		}
+		t.Proxy = http.ProxyURL(u)
+		c.client.Transport = t
+	}
+}
+
+// WithRetry retries transient failures under p
+func WithRetry(p retry.Policy) Option {
+	return func(c *clientConfig) {
+		c.client.Retry = &p
+	}
+}
+
+// WithBreaker fast-fails requests to failing hosts
+func WithBreaker(b *retry.Breaker) Option {
+	return func(c *clientConfig) {
+		c.client.Breaker = b
+	}
+}
+
+// WithLimiter throttles requests before they are sent
+func WithLimiter(l ratelimit.RequestLimiter) Option {
+	return func(c *clientConfig) {
+		c.client.Limiter = l
+	}
+}
+
+// WithProfiles sets the named profiles selected per request with WithProfile
+func WithProfiles(profiles map[string]Profile) Option {
+	return func(c *clientConfig) {
+		c.client.Profiles = profiles
+	}
+}
+
+// WithLiveConfig reads reloadable settings from l on every request
+func WithLiveConfig(l *LiveConfig) Option {
+	return func(c *clientConfig) {
+		c.client.Live = l
+	}
+}
+
+// WithFlags switches optional behaviors through p
+func WithFlags(p FlagProvider) Option {
+	return func(c *clientConfig) {
+		c.client.Flags = p
+	}
+}
+
+// WithEvents publishes client activity on bus
+func WithEvents(bus *observe.EventBus) Option {
+	return func(c *clientConfig) {
+		c.client.Events = bus
+	}
+}
+
+// WithTransformers appends response body transformers
+func WithTransformers(ts ...Transformer) Option {
+	return func(c *clientConfig) {
+		c.client.Transformers = append(c.client.Transformers, ts...)
+	}
+}
+
+// WithCookieJar stores and sends cookies through jar
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(c *clientConfig) {
+		c.client.Jar = jar
+	}
+}
+
+// WithCheckRedirect sets the redirect policy, as on http.Client
+func WithCheckRedirect(fn func(req *http.Request, via []*http.Request) error) Option {
+	return func(c *clientConfig) {
+		c.client.CheckRedirect = fn
+	}
+}
+
+// WithMiddleware appends middleware around the transport
+func WithMiddleware(mws ...Middleware) Option {
+	return func(c *clientConfig) {
+		c.client.Middleware = append(c.client.Middleware, mws...)
+	}
+}
+
+// WithHeaderFunc sets the name header of every request without it to the value fn computes
+func WithHeaderFunc(name string, fn HeaderFunc) Option {
+	return func(c *clientConfig) {
+		if c.client.HeaderFuncs == nil {
+			c.client.HeaderFuncs = make(map[string]HeaderFunc)
+		}
+		c.client.HeaderFuncs[name] = fn
+	}
+}
+
+// WithLocales asks for locales in order of preference on every request without
+// an Accept-Language header; see AcceptLanguage
+func WithLocales(locales ...string) Option {
+	v := AcceptLanguage(locales...)
+	return WithHeaderFunc("Accept-Language", func(context.Context) string { return v })
+}
+
+// WithHooks runs h around every request
+func WithHooks(h Hooks) Option {
+	return func(c *clientConfig) {
+		c.client.Hooks = h
+	}
+}
+
+// WithDryRun answers requests from d instead of the network
+func WithDryRun(d *DryRun) Option {
+	return func(c *clientConfig) {
+		c.client.DryRun = d
+	}
+}
+
+// WithVersioning sets the API version of every request and watches for deprecations
+func WithVersioning(v *Versioning) Option {
+	return func(c *clientConfig) {
+		c.client.Versioning = v
+	}
+}
+
+// WithLogger sends the client's log output to l
+func WithLogger(l Logger) Option {
+	return func(c *clientConfig) {
+		c.client.Logger = l
+	}
+}
+
+// WithDev marks the client as a local development client
+func WithDev() Option {
+	return func(c *clientConfig) {
+		c.client.Dev = true
+	}
+}
+
+// WithStrict makes NewClientWithOptions fail when Validate reports problems
+func WithStrict() Option {
+	return func(c *clientConfig) {
+		c.strict = true
+	}
+}
+
+// fail records the first option error
+func (c *clientConfig) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
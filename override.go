@@ -0,0 +1,37 @@
+package httplib
+
+import "net/http"
+
+// MethodOverrideHeader carries the real method of a tunnelled request
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// WithMethodOverride wraps next so PUT, PATCH and DELETE requests are sent as POST
+// with the original verb in X-HTTP-Method-Override, for proxies and legacy
+// gateways that only let GET and POST through. a nil next uses http.DefaultTransport
+//
+//	c := NewClient{Transport: WithMethodOverride(nil), Timeout: 10 * time.Second}
+func WithMethodOverride(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return methodOverride{next: next}
+}
+
+type methodOverride struct {
+	next http.RoundTripper
+}
+
+// RoundTrip rewrites overridable methods on a copy of req
+func (m methodOverride) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return m.next.RoundTrip(req)
+	}
+
+	// a RoundTripper must not modify the caller's request
+	r := req.Clone(req.Context())
+	r.Header.Set(MethodOverrideHeader, req.Method)
+	r.Method = http.MethodPost
+	return m.next.RoundTrip(r)
+}
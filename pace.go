@@ -0,0 +1,36 @@
+package httplib
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/clairmont32/httplib/ratelimit"
+)
+
+// WithDelayBetweenRequests wraps next so requests through it start at least d
+// apart, for vendors that literally allow one request per interval. it is a
+// ratelimit.LeakyBucket in front of next: no bursts, just spacing. waits honor
+// the request context. a nil next uses http.DefaultTransport
+//
+//	SetDefaultClient(&NewClient{Transport: WithDelayBetweenRequests(nil, time.Second), Timeout: 10 * time.Second})
+func WithDelayBetweenRequests(next http.RoundTripper, d time.Duration) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return pacedTransport{next: next, bucket: ratelimit.NewLeakyBucket(d)}
+}
+
+type pacedTransport struct {
+	next   http.RoundTripper
+	bucket *ratelimit.LeakyBucket
+}
+
+func (t pacedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.bucket.Wait(req.Context()); err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,201 @@
+package httplib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/clairmont32/httplib/internal/jsonfile"
+)
+
+// Paginator walks a cursor paginated endpoint one page at a time.
+// with Checkpoints set the cursor is saved after every handled page,
+// so an export that crashed resumes where it stopped instead of starting over
+type Paginator struct {
+	// Request is the first page; it is cloned for every page
+	Request FormRequest
+	Headers []Headers
+
+	// Apply sets cursor on the request for a following page, e.g. as a query parameter
+	Apply func(req *FormRequest, cursor string)
+	// Next returns the cursor of the page after this one, or "" on the last page
+	Next func(body []byte, header http.Header) (string, error)
+
+	// Checkpoints stores progress under Key; nil disables resuming
+	Checkpoints CheckpointStore
+	Key         string
+
+	// Client performs the requests; nil uses the default client
+	Client *NewClient
+
+	// Delay waits between page requests, for APIs that allow one request per
+	// interval; WithDelayBetweenRequests paces a whole client instead
+	Delay time.Duration
+}
+
+// CheckpointStore persists pagination cursors between runs
+type CheckpointStore interface {
+	Load(key string) (cursor string, ok bool, err error)
+	Save(key, cursor string) error
+	Clear(key string) error
+}
+
+// Each calls handle with the body of every page in order.
+// when a checkpoint exists it starts from the saved cursor; the checkpoint
+// is cleared once the last page was handled
+func (p Paginator) Each(ctx context.Context, handle func(page []byte) error) error {
+	if p.Apply == nil || p.Next == nil {
+		return errors.New("Paginator requires Apply and Next funcs")
+	}
+	if p.Checkpoints != nil && p.Key == "" {
+		return errors.New("Paginator with Checkpoints requires a Key")
+	}
+
+	var cursor string
+	if p.Checkpoints != nil {
+		saved, ok, err := p.Checkpoints.Load(p.Key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			cursor = saved
+		}
+	}
+
+	for {
+		page := p.Request.Clone()
+		if cursor != "" {
+			p.Apply(&page, cursor)
+		}
+
+		body, header, err := p.fetch(ctx, &page)
+		if err != nil {
+			return err
+		}
+		if err := handle(body); err != nil {
+			return err
+		}
+
+		next, err := p.Next(body, header)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			if p.Checkpoints != nil {
+				return p.Checkpoints.Clear(p.Key)
+			}
+			return nil
+		}
+		if p.Checkpoints != nil {
+			if err := p.Checkpoints.Save(p.Key, next); err != nil {
+				return err
+			}
+		}
+		cursor = next
+		if p.Delay > 0 {
+			if err := sleepCtx(ctx, p.Delay); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (p Paginator) fetch(ctx context.Context, page *FormRequest) ([]byte, http.Header, error) {
+	req, err := page.FormRequestWithContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, h := range p.Headers {
+		h.AddHeader(req)
+	}
+
+	resp, err := doWith(p.Client, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := ProcessStatusCode(resp)
+	return body, resp.Header, err
+}
+
+// MemoryCheckpointStore keeps cursors in memory; the zero value is ready to use
+type MemoryCheckpointStore struct {
+	mu      sync.Mutex
+	cursors map[string]string
+}
+
+// Load returns the cursor saved under key
+func (m *MemoryCheckpointStore) Load(key string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.cursors[key]
+	return c, ok, nil
+}
+
+// Save stores cursor under key
+func (m *MemoryCheckpointStore) Save(key, cursor string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cursors == nil {
+		m.cursors = make(map[string]string)
+	}
+	m.cursors[key] = cursor
+	return nil
+}
+
+// Clear forgets the cursor under key
+func (m *MemoryCheckpointStore) Clear(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.cursors, key)
+	return nil
+}
+
+// FileCheckpointStore keeps cursors in a JSON file that is rewritten atomically
+// on every change, so a crash never leaves a corrupt checkpoint
+type FileCheckpointStore struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// Load returns the cursor saved under key
+func (f *FileCheckpointStore) Load(key string) (string, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cursors, err := f.read()
+	if err != nil {
+		return "", false, err
+	}
+	c, ok := cursors[key]
+	return c, ok, nil
+}
+
+// Save stores cursor under key
+func (f *FileCheckpointStore) Save(key, cursor string) error {
+	return f.update(func(cursors map[string]string) { cursors[key] = cursor })
+}
+
+// Clear forgets the cursor under key
+func (f *FileCheckpointStore) Clear(key string) error {
+	return f.update(func(cursors map[string]string) { delete(cursors, key) })
+}
+
+// update re-reads the file so several processes exporting different keys can share it
+func (f *FileCheckpointStore) update(fn func(map[string]string)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cursors, err := f.read()
+	if err != nil {
+		return err
+	}
+	fn(cursors)
+	return jsonfile.Write(f.Path, cursors)
+}
+
+func (f *FileCheckpointStore) read() (map[string]string, error) {
+	cursors := make(map[string]string)
+	err := jsonfile.Read(f.Path, &cursors)
+	return cursors, err
+}
@@ -0,0 +1,40 @@
+package httplib
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicError is returned in place of a panic raised by user supplied code on the
+// request path, such as a transport wrapper or a Transformer, so one buggy hook
+// fails that request instead of crashing the service. use errors.As to get it
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("recovered panic: %v", e.Value)
+}
+
+// recoverInto turns a panic into a *PanicError stored in err; call it deferred
+func recoverInto(err *error) {
+	if v := recover(); v != nil {
+		*err = &PanicError{Value: v, Stack: debug.Stack()}
+	}
+}
+
+// recoverTransport converts panics in next, and everything it wraps, into errors
+type recoverTransport struct {
+	next http.RoundTripper
+}
+
+func (t recoverTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	defer recoverInto(&err)
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return next.RoundTrip(req)
+}
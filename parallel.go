@@ -0,0 +1,77 @@
+package httplib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Result holds the outcome of a single URL fetched by ParallelGet
+type Result struct {
+	URL  string
+	Body []byte
+	Err  error
+}
+
+// ParallelGet fetches urls with at most concurrency requests in flight
+// and returns one Result per URL in the same order as urls.
+// a failing URL does not stop the others; its error is set on its Result.
+// the returned error is only set when concurrency is invalid or ctx ends early
+func ParallelGet(ctx context.Context, urls []string, concurrency int) ([]Result, error) {
+	return ParallelGetWithProgress(ctx, urls, concurrency, nil)
+}
+
+// ParallelGetWithProgress is ParallelGet calling progress each time a URL completes
+// with the number finished so far out of len(urls). progress may be nil
+func ParallelGetWithProgress(ctx context.Context, urls []string, concurrency int, progress ProgressFunc) ([]Result, error) {
+	if concurrency < 1 {
+		return nil, errors.New("concurrency must be at least 1")
+	}
+
+	results := make([]Result, len(urls))
+	var (
+		mu       sync.Mutex
+		finished int64
+	)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+
+	for i, u := range urls {
+		i, u := i, u
+		results[i].URL = u
+		g.Go(func() error {
+			// don't start new requests once the caller gave up
+			if err := gctx.Err(); err != nil {
+				results[i].Err = err
+				return nil
+			}
+			results[i].Body, results[i].Err = get(gctx, u)
+			if progress != nil {
+				mu.Lock()
+				finished++
+				progress(finished, int64(len(urls)))
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	_ = g.Wait() // workers never return errors; they are kept per result
+
+	return results, ctx.Err()
+}
+
+// get performs a single GET through the default client
+func get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, _, err := DefaultClient(req)
+	if err != nil {
+		return nil, err
+	}
+	return ProcessStatusCode(resp)
+}
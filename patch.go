@@ -0,0 +1,182 @@
+package httplib
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Content types for PATCH bodies
+const (
+	ContentTypeJSONPatch  = "application/json-patch+json"
+	ContentTypeMergePatch = "application/merge-patch+json"
+)
+
+// PatchOp is a single RFC 6902 JSON Patch operation
+type PatchOp struct {
+	Op    string
+	Path  string
+	Value interface{}
+}
+
+// MarshalJSON omits value only for remove, so add and replace can set null
+func (p PatchOp) MarshalJSON() ([]byte, error) {
+	if p.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{p.Op, p.Path})
+	}
+	return json.Marshal(struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}{p.Op, p.Path, p.Value})
+}
+
+// MergePatch computes the RFC 7396 merge patch turning before into after.
+// both values are compared by their JSON encoding; removed fields become null
+// and arrays are replaced whole as the RFC requires.
+// merge patches cannot set a field to null, use JSONPatch when that matters
+func MergePatch(before, after interface{}) ([]byte, error) {
+	b, a, err := toJSONValues(before, after)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeDiff(b, a))
+}
+
+// JSONPatch computes RFC 6902 operations turning before into after.
+// objects are diffed key by key; arrays and scalars that differ are replaced
+func JSONPatch(before, after interface{}) ([]byte, error) {
+	b, a, err := toJSONValues(before, after)
+	if err != nil {
+		return nil, err
+	}
+	ops := jsonPatchDiff("", b, a, []PatchOp{})
+	return json.Marshal(ops)
+}
+
+// SendMergePatch sends the merge patch from before to after as a PATCH to baseURL+endpoint
+func SendMergePatch(baseURL, endpoint string, before, after interface{}, headers []Headers) ([]byte, error) {
+	patch, err := MergePatch(before, after)
+	if err != nil {
+		return nil, err
+	}
+	return sendPatch(baseURL, endpoint, patch, ContentTypeMergePatch, headers)
+}
+
+// SendJSONPatch sends the JSON Patch from before to after as a PATCH to baseURL+endpoint
+func SendJSONPatch(baseURL, endpoint string, before, after interface{}, headers []Headers) ([]byte, error) {
+	patch, err := JSONPatch(before, after)
+	if err != nil {
+		return nil, err
+	}
+	return sendPatch(baseURL, endpoint, patch, ContentTypeJSONPatch, headers)
+}
+
+func sendPatch(baseURL, endpoint string, patch []byte, contentType string, headers []Headers) ([]byte, error) {
+	req := &FormRequest{
+		BaseURL:  baseURL,
+		Endpoint: endpoint,
+		Payload:  patch,
+		Method:   http.MethodPatch,
+	}
+	all := append([]Headers{{Key: "Content-Type", Value: contentType}}, headers...)
+	return DefaultRequest(req, all)
+}
+
+// toJSONValues converts both values to their generic JSON form
+func toJSONValues(before, after interface{}) (interface{}, interface{}, error) {
+	b, err := toJSONValue(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := toJSONValue(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, a, nil
+}
+
+func toJSONValue(v interface{}) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber() // keep large integers exact
+	var out interface{}
+	err = dec.Decode(&out)
+	return out, err
+}
+
+func mergeDiff(before, after interface{}) interface{} {
+	bObj, bok := before.(map[string]interface{})
+	aObj, aok := after.(map[string]interface{})
+	if !bok || !aok {
+		return after
+	}
+
+	patch := map[string]interface{}{}
+	for k, bv := range bObj {
+		av, ok := aObj[k]
+		switch {
+		case !ok:
+			patch[k] = nil
+		case !reflect.DeepEqual(bv, av):
+			patch[k] = mergeDiff(bv, av)
+		}
+	}
+	for k, av := range aObj {
+		if _, ok := bObj[k]; !ok {
+			patch[k] = av
+		}
+	}
+	return patch
+}
+
+func jsonPatchDiff(path string, before, after interface{}, ops []PatchOp) []PatchOp {
+	if reflect.DeepEqual(before, after) {
+		return ops
+	}
+
+	bObj, bok := before.(map[string]interface{})
+	aObj, aok := after.(map[string]interface{})
+	if !bok || !aok {
+		return append(ops, PatchOp{Op: "replace", Path: path, Value: after})
+	}
+
+	for _, k := range sortedKeys(bObj) {
+		p := path + "/" + escapePointer(k)
+		av, ok := aObj[k]
+		if !ok {
+			ops = append(ops, PatchOp{Op: "remove", Path: p})
+			continue
+		}
+		ops = jsonPatchDiff(p, bObj[k], av, ops)
+	}
+	for _, k := range sortedKeys(aObj) {
+		if _, ok := bObj[k]; !ok {
+			ops = append(ops, PatchOp{Op: "add", Path: path + "/" + escapePointer(k), Value: aObj[k]})
+		}
+	}
+	return ops
+}
+
+// escapePointer escapes a key for use in a JSON Pointer (RFC 6901)
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,47 @@
+package httplib
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ExpandPath substitutes the {name} placeholders of template with the path
+// escaped params, so "/users/{id}" with id "a/b" becomes "/users/a%2Fb".
+// placeholders without a param and params without a placeholder are errors,
+// which catches typos on either side, as are empty, "." and ".." values
+func ExpandPath(template string, params map[string]string) (string, error) {
+	var b strings.Builder
+	used := make(map[string]bool, len(params))
+	rest := template
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			b.WriteString(rest)
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("path %q: unclosed placeholder", template)
+		}
+		name := rest[open+1 : open+end]
+		v, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("path %q: no value for {%s}", template, name)
+		}
+		if v == "." || v == ".." || v == "" {
+			// these would change or drop the segment instead of filling it
+			return "", fmt.Errorf("path %q: invalid value %q for {%s}", template, v, name)
+		}
+		used[name] = true
+		b.WriteString(rest[:open])
+		b.WriteString(url.PathEscape(v))
+		rest = rest[open+end+1:]
+	}
+	for name := range params {
+		if !used[name] {
+			return "", fmt.Errorf("path %q: no placeholder for param %q", template, name)
+		}
+	}
+	return b.String(), nil
+}
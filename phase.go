@@ -0,0 +1,100 @@
+package httplib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Phase is the part of a request's lifecycle it was in when it failed
+type Phase string
+
+// phases in the order a request goes through them
+const (
+	PhaseConnWait       Phase = "waiting for connection"
+	PhaseDNS            Phase = "dns lookup"
+	PhaseDial           Phase = "dialing"
+	PhaseTLS            Phase = "tls handshake"
+	PhaseWriteRequest   Phase = "writing request"
+	PhaseWaitingHeaders Phase = "waiting for headers"
+	PhaseReadingBody    Phase = "reading body"
+)
+
+// PhaseError reports the phase a cancelled or timed out request died in.
+// requests are wrapped inside their *url.Error, so existing checks keep working;
+// use errors.As to get it
+type PhaseError struct {
+	Phase Phase
+	// Elapsed is the time from sending the request to the failure
+	Elapsed time.Duration
+	Err     error
+}
+
+func (e *PhaseError) Error() string {
+	return fmt.Sprintf("%s after %s: %v", e.Phase, e.Elapsed.Round(time.Millisecond), e.Err)
+}
+
+func (e *PhaseError) Unwrap() error { return e.Err }
+
+// Timeout reports whether the wrapped error is a timeout. url.Error asks its
+// direct cause rather than unwrapping, so the wrapper has to answer for it
+func (e *PhaseError) Timeout() bool {
+	return isTimeout(e.Err)
+}
+
+func isTimeout(err error) bool {
+	var t interface{ Timeout() bool }
+	return errors.As(err, &t) && t.Timeout()
+}
+
+// phase returns where a request that has got this far stopped
+func (p tracePhases) phase() Phase {
+	switch {
+	case !p.wroteRequest.IsZero():
+		return PhaseWaitingHeaders
+	case !p.gotConn.IsZero():
+		return PhaseWriteRequest
+	case !p.tlsStart.IsZero() && p.TLS == 0:
+		return PhaseTLS
+	case !p.connectStart.IsZero() && p.Connect == 0:
+		return PhaseDial
+	case !p.dnsStart.IsZero() && p.DNS == 0:
+		return PhaseDNS
+	}
+	return PhaseConnWait
+}
+
+// withPhase annotates err with the phase of t when req was cancelled or timed out
+func withPhase(req *http.Request, t *traceTimings, err error) error {
+	if err == nil || (req.Context().Err() == nil && !isTimeout(err)) {
+		return err
+	}
+	p := t.snapshot()
+	perr := &PhaseError{Phase: p.phase(), Elapsed: time.Since(p.start)}
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		perr.Err = uerr.Err
+		uerr.Err = perr
+		return err
+	}
+	perr.Err = err
+	return perr
+}
+
+// phaseBody reports body reads cut short by cancellation or a timeout as a *PhaseError
+type phaseBody struct {
+	io.ReadCloser
+	req   *http.Request
+	start time.Time
+}
+
+func (b *phaseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && (b.req.Context().Err() != nil || isTimeout(err)) {
+		err = &PhaseError{Phase: PhaseReadingBody, Elapsed: time.Since(b.start), Err: err}
+	}
+	return n, err
+}
@@ -0,0 +1,88 @@
+package httplib
+
+import (
+	"context"
+	"sync"
+)
+
+// Prefetcher fetches URLs ahead of a sequential consumer to hide latency,
+// e.g. the next pages of a paginated export.
+// results are handed to the consumer in the order Next produced the URLs
+type Prefetcher struct {
+	// Next returns the next URL to fetch, ok is false once there are no more.
+	// it is only ever called from a single goroutine
+	Next func() (url string, ok bool)
+
+	// Ahead bounds how many URLs are fetched or waiting for the consumer at once
+	// values below 1 mean 1
+	Ahead int
+}
+
+// Run fetches URLs from Next and calls handle with each Result in order.
+// it stops at the first error returned by handle or when ctx is done
+// and waits for outstanding fetches before returning
+func (p Prefetcher) Run(ctx context.Context, handle func(Result) error) error {
+	ahead := p.Ahead
+	if ahead < 1 {
+		ahead = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	// cancel first so the producer and fetches unblock before we wait on them
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// sem bounds outstanding results; slots carries them to the consumer in order
+	sem := make(chan struct{}, ahead)
+	slots := make(chan chan Result, ahead)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(slots)
+		for {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			u, ok := p.Next()
+			if !ok {
+				return
+			}
+
+			slot := make(chan Result, 1)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				body, err := get(ctx, u)
+				slot <- Result{URL: u, Body: body, Err: err}
+			}()
+
+			select {
+			case slots <- slot:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for slot := range slots {
+		var r Result
+		select {
+		case r = <-slot:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		<-sem
+
+		if err := handle(r); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
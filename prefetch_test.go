@@ -0,0 +1,42 @@
+package httplib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPrefetcherRunStopsOnHandleError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	n := 0
+	p := Prefetcher{
+		Next: func() (string, bool) {
+			n++
+			return fmt.Sprintf("%s/%d", srv.URL, n), true
+		},
+		Ahead: 2,
+	}
+
+	errStop := errors.New("stop")
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Run(context.Background(), func(Result) error { return errStop })
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, errStop) {
+			t.Fatalf("Run returned %v, want %v", err, errStop)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after handle failed")
+	}
+}
@@ -0,0 +1,202 @@
+package httplib
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Preflight checks that hosts accept TCP connections, and TLS handshakes for
+// https targets, before a long batch job starts, so an unreachable upstream is
+// reported up front instead of failing the job halfway
+//
+//	report := httplib.Preflight{}.Check(ctx, "https://api.example.com", "db.internal:5432")
+//	if !report.OK() {
+//		log.Fatal(report)
+//	}
+type Preflight struct {
+	// Dialer opens the connections; nil uses a plain net.Dialer
+	Dialer *Dialer
+	// TLSConfig is the base client config of handshakes; ServerName is set per target
+	TLSConfig *tls.Config
+	// Timeout bounds each target; zero uses 5s
+	Timeout time.Duration
+	// Concurrency is how many targets are checked at once; zero uses 8
+	Concurrency int
+}
+
+// Reachability is the preflight result of one target
+type Reachability struct {
+	Target string
+	// Address is the host:port dialed
+	Address string
+	// Addrs are the addresses the host resolved to
+	Addrs   []string
+	DNS     time.Duration
+	Connect time.Duration
+	// TLS is zero for targets checked without a handshake
+	TLS time.Duration
+	// Phase is where the check failed, PhaseDNS, PhaseDial or PhaseTLS; empty
+	// on success and for targets that could not be parsed
+	Phase Phase
+	Err   error
+}
+
+// PreflightReport holds the results in the order the targets were given
+type PreflightReport []Reachability
+
+// OK reports whether every target was reachable
+func (r PreflightReport) OK() bool {
+	return len(r.Failed()) == 0
+}
+
+// Failed returns the unreachable targets
+func (r PreflightReport) Failed() []Reachability {
+	var failed []Reachability
+	for _, t := range r {
+		if t.Err != nil {
+			failed = append(failed, t)
+		}
+	}
+	return failed
+}
+
+// String lists every target with its timing or failure, one per line
+func (r PreflightReport) String() string {
+	var b strings.Builder
+	for _, t := range r {
+		switch {
+		case t.Err != nil && t.Phase == "":
+			fmt.Fprintf(&b, "%s: invalid: %v\n", t.Target, t.Err)
+			continue
+		case t.Err != nil:
+			fmt.Fprintf(&b, "%s: unreachable, %s failed: %v\n", t.Target, t.Phase, t.Err)
+			continue
+		}
+		fmt.Fprintf(&b, "%s: ok, dns %s connect %s", t.Target, roundMS(t.DNS), roundMS(t.Connect))
+		if t.TLS > 0 {
+			fmt.Fprintf(&b, " tls %s", roundMS(t.TLS))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func roundMS(d time.Duration) time.Duration { return d.Round(time.Millisecond) }
+
+// Check probes every target, a URL or a host:port. https and wss URLs and
+// port 443 get a TLS handshake after connecting; everything else only TCP
+func (p Preflight) Check(ctx context.Context, targets ...string) PreflightReport {
+	limit := p.Concurrency
+	if limit <= 0 {
+		limit = 8
+	}
+	report := make(PreflightReport, len(targets))
+	var g errgroup.Group
+	g.SetLimit(limit)
+	for i, t := range targets {
+		i, t := i, t
+		g.Go(func() error {
+			report[i] = p.check(ctx, t)
+			return nil
+		})
+	}
+	_ = g.Wait() // failures are kept per target
+	return report
+}
+
+func (p Preflight) check(ctx context.Context, target string) Reachability {
+	r := Reachability{Target: target}
+	host, port, useTLS, err := preflightAddress(target)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	r.Address = net.JoinHostPort(host, port)
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	r.DNS = time.Since(start)
+	if err != nil {
+		r.Phase, r.Err = PhaseDNS, err
+		return r
+	}
+	r.Addrs = addrs
+
+	start = time.Now()
+	var conn net.Conn
+	if p.Dialer != nil {
+		conn, err = p.Dialer.DialContext(ctx, "tcp", r.Address)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", r.Address)
+	}
+	r.Connect = time.Since(start)
+	if err != nil {
+		r.Phase, r.Err = PhaseDial, err
+		return r
+	}
+	defer conn.Close()
+	if !useTLS {
+		return r
+	}
+
+	cfg := &tls.Config{}
+	if p.TLSConfig != nil {
+		cfg = p.TLSConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+	start = time.Now()
+	tc := tls.Client(conn, cfg)
+	err = tc.HandshakeContext(ctx)
+	r.TLS = time.Since(start)
+	if err != nil {
+		r.Phase, r.Err = PhaseTLS, err
+		return r
+	}
+	// say goodbye properly so servers do not log a broken handshake
+	_ = tc.Close()
+	return r
+}
+
+// preflightAddress splits a URL or host:port target into what to dial
+func preflightAddress(target string) (host, port string, useTLS bool, err error) {
+	if !strings.Contains(target, "://") {
+		host, port, err = net.SplitHostPort(target)
+		if err != nil {
+			return "", "", false, fmt.Errorf("preflight target %q: %w", target, err)
+		}
+		return host, port, port == "443", nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", "", false, err
+	}
+	useTLS = u.Scheme == "https" || u.Scheme == "wss"
+	host, port = u.Hostname(), u.Port()
+	if host == "" {
+		return "", "", false, fmt.Errorf("preflight target %q has no host", target)
+	}
+	if port == "" {
+		port = "80"
+		if useTLS {
+			port = "443"
+		}
+	}
+	return host, port, useTLS, nil
+}
@@ -0,0 +1,83 @@
+package httplib
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/clairmont32/httplib/retry"
+)
+
+// Profile overrides client settings for a class of endpoints, e.g.
+//
+//	c := NewClient{Timeout: 10 * time.Second, Profiles: map[string]Profile{
+//		"search": {Timeout: 2 * time.Second, Retry: &retry.Policy{MaxAttempts: 1}},
+//		"bulk":   {Timeout: 120 * time.Second, Retry: &retry.Policy{MaxAttempts: 5, Base: time.Second}},
+//	}}
+//	resp, _, err := c.DoRequest(WithProfile(req, "search"))
+type Profile struct {
+	// Timeout replaces the client timeout when set
+	Timeout time.Duration
+	// Budget cancels requests still running after it, even when the timeout is
+	// larger, and reports them as a *BudgetExceededError rather than a timeout
+	Budget time.Duration
+	// Headers are added to every request sent with the profile
+	Headers []Headers
+	// Transformers run on response bodies after the client transformers
+	Transformers []Transformer
+	// Retry replaces the client retry policy when set; WithRetryPolicy on the
+	// request context still wins
+	Retry *retry.Policy
+}
+
+// WithProfile returns a copy of req that uses the named profile of the client
+// it is sent with. sending it through a client without that profile fails
+func WithProfile(req *http.Request, name string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), profileKey, name))
+}
+
+func profileName(req *http.Request) (string, bool) {
+	name, ok := req.Context().Value(profileKey).(string)
+	return name, ok
+}
+
+// apply returns req with the profile headers and retry policy added, leaving
+// the caller's request untouched
+func (p Profile) apply(req *http.Request) *http.Request {
+	if p.Retry != nil {
+		if _, ok := req.Context().Value(retryKey).(retry.Policy); !ok {
+			req = req.WithContext(WithRetryPolicy(req.Context(), *p.Retry))
+		}
+	}
+	if len(p.Headers) == 0 {
+		return req
+	}
+	r := req.Clone(req.Context())
+	for _, h := range p.Headers {
+		h.AddHeader(r)
+	}
+	return r
+}
+
+// mergeProfiles overlays reloaded profiles on the static ones without modifying either map
+func mergeProfiles(static, live map[string]Profile) map[string]Profile {
+	if len(live) == 0 {
+		return static
+	}
+	merged := make(map[string]Profile, len(static)+len(live))
+	for name, p := range static {
+		merged[name] = p
+	}
+	for name, p := range live {
+		// reloaded profiles come from config files, which cannot carry funcs
+		// or retry policies
+		if len(p.Transformers) == 0 {
+			p.Transformers = static[name].Transformers
+		}
+		if p.Retry == nil {
+			p.Retry = static[name].Retry
+		}
+		merged[name] = p
+	}
+	return merged
+}
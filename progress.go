@@ -0,0 +1,145 @@
+package httplib
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProgressFunc receives progress updates as done units out of total.
+// units are bytes for transfers and URLs for batches; total is -1 when unknown.
+// any progress bar can be driven from it, e.g. func(d, t int64) { bar.ChangeMax64(t); bar.Set64(d) }
+type ProgressFunc func(done, total int64)
+
+// progressReader reports bytes read through it
+type progressReader struct {
+	r     io.ReadCloser
+	total int64
+	done  int64
+	fn    ProgressFunc
+}
+
+// NewProgressReader wraps r so fn is called after every read with the bytes read so far.
+// at EOF an unknown total is reported as the final count
+func NewProgressReader(r io.ReadCloser, total int64, fn ProgressFunc) io.ReadCloser {
+	return &progressReader{r: r, total: total, fn: fn}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.done += int64(n)
+	switch {
+	case err == io.EOF && p.total != p.done:
+		// the length is known now, report completion once
+		p.total = p.done
+		p.fn(p.done, p.total)
+	case n > 0:
+		p.fn(p.done, p.total)
+	}
+	return n, err
+}
+
+func (p *progressReader) Close() error {
+	return p.r.Close()
+}
+
+// DownloadProgress wraps the body of resp so fn sees the download progress as it is read
+func DownloadProgress(resp *http.Response, fn ProgressFunc) *http.Response {
+	if resp.Body != nil {
+		resp.Body = NewProgressReader(resp.Body, resp.ContentLength, fn)
+	}
+	return resp
+}
+
+// UploadProgress wraps the body of req so fn sees the upload progress as it is sent.
+// req is modified and returned
+func UploadProgress(req *http.Request, fn ProgressFunc) *http.Request {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req
+	}
+	total := req.ContentLength
+	if total == 0 {
+		total = -1
+	}
+	req.Body = NewProgressReader(req.Body, total, fn)
+	if getBody := req.GetBody; getBody != nil {
+		// a body replayed for a redirect starts from zero again
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return NewProgressReader(body, total, fn), nil
+		}
+	}
+	return req
+}
+
+// TextProgress returns a ProgressFunc rendering a single updating line of
+// byte counts to w, e.g. a terminal, at most every 100ms plus once on completion
+//
+//	export  42% [########          ] 4.2MB/10.0MB
+func TextProgress(w io.Writer, label string) ProgressFunc {
+	return textProgress(w, label, formatBytes)
+}
+
+// TextItemProgress is TextProgress for counts of items, e.g. the URLs of a batch
+//
+//	fetch  42% [########          ] 42/100
+func TextItemProgress(w io.Writer, label string) ProgressFunc {
+	return textProgress(w, label, func(n int64) string { return strconv.FormatInt(n, 10) })
+}
+
+func textProgress(w io.Writer, label string, format func(int64) string) ProgressFunc {
+	var (
+		mu   sync.Mutex
+		last time.Time
+	)
+	return func(done, total int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		complete := total >= 0 && done >= total
+		if !complete && time.Since(last) < 100*time.Millisecond {
+			return
+		}
+		last = time.Now()
+
+		if total < 0 {
+			fmt.Fprintf(w, "\r%s %s", label, format(done))
+			return
+		}
+		const width = 20
+		frac := 1.0
+		if total > 0 {
+			frac = float64(done) / float64(total)
+		}
+		filled := int(frac * width)
+		if filled > width {
+			filled = width
+		}
+		fmt.Fprintf(w, "\r%s %3.0f%% [%s%s] %s/%s", label, frac*100,
+			strings.Repeat("#", filled), strings.Repeat(" ", width-filled),
+			format(done), format(total))
+		if complete {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// formatBytes renders a byte count compactly
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
@@ -0,0 +1,150 @@
+package httplib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ContentRange is a parsed Content-Range header. End is inclusive and Size
+// is -1 when the server did not give the full length
+type ContentRange struct {
+	Start int64
+	End   int64
+	Size  int64
+}
+
+// ErrRangeNotSatisfiable is returned by GetRange for 416 responses
+var ErrRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// ParseContentRange parses a "bytes start-end/size" Content-Range value
+func ParseContentRange(v string) (ContentRange, error) {
+	unit, spec, ok := strings.Cut(strings.TrimSpace(v), " ")
+	if !ok || unit != "bytes" {
+		return ContentRange{}, fmt.Errorf("content range %q: unsupported unit", v)
+	}
+	rng, size, ok := strings.Cut(spec, "/")
+	if !ok {
+		return ContentRange{}, fmt.Errorf("content range %q: missing size", v)
+	}
+	cr := ContentRange{Size: -1}
+	if size != "*" {
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil || n < 0 {
+			return ContentRange{}, fmt.Errorf("content range %q: invalid size", v)
+		}
+		cr.Size = n
+	}
+	first, last, ok := strings.Cut(rng, "-")
+	if !ok {
+		return ContentRange{}, fmt.Errorf("content range %q: invalid range", v)
+	}
+	var err1, err2 error
+	cr.Start, err1 = strconv.ParseInt(first, 10, 64)
+	cr.End, err2 = strconv.ParseInt(last, 10, 64)
+	if err1 != nil || err2 != nil || cr.Start < 0 || cr.End < cr.Start || (cr.Size >= 0 && cr.End >= cr.Size) {
+		return ContentRange{}, fmt.Errorf("content range %q: invalid range", v)
+	}
+	return cr, nil
+}
+
+// GetRange GETs the bytes start to end, inclusive, of url through the default
+// client, e.g. GetRange(ctx, u, 0, 511) for a file header. a negative end reads
+// to the end and a negative start reads the last -start bytes, so
+// GetRange(ctx, u, -128, -1) is the footer. servers ignoring the range answer
+// with the whole body, which is then cut locally without reading past end
+func GetRange(ctx context.Context, url string, start, end int64) ([]byte, ContentRange, error) {
+	if end >= 0 && (start < 0 || end < start) {
+		return nil, ContentRange{}, fmt.Errorf("invalid range %d-%d", start, end)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, ContentRange{}, err
+	}
+	req.Header.Set("Range", rangeHeader(start, end))
+
+	resp, _, err := sharedClient().DoRequest(req)
+	if err != nil {
+		return nil, ContentRange{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		cr, err := ParseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return nil, ContentRange{}, err
+		}
+		if start >= 0 && cr.Start != start {
+			return nil, cr, fmt.Errorf("server returned range %d-%d for requested start %d", cr.Start, cr.End, start)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, cr, err
+		}
+		if int64(len(body)) != cr.End-cr.Start+1 {
+			return nil, cr, fmt.Errorf("range %d-%d: got %d bytes", cr.Start, cr.End, len(body))
+		}
+		return body, cr, nil
+	case http.StatusOK:
+		return cutRange(resp, start, end)
+	case http.StatusRequestedRangeNotSatisfiable:
+		return nil, ContentRange{}, ErrRangeNotSatisfiable
+	default:
+		var body []byte
+		if ErrorBody.MaxBody > 0 {
+			// keep one byte over the cap so the snippet is marked as truncated
+			body, _ = io.ReadAll(io.LimitReader(resp.Body, int64(ErrorBody.MaxBody)+1))
+		}
+		return nil, ContentRange{}, statusError(resp, body)
+	}
+}
+
+func rangeHeader(start, end int64) string {
+	switch {
+	case start < 0:
+		return fmt.Sprintf("bytes=%d", start)
+	case end < 0:
+		return fmt.Sprintf("bytes=%d-", start)
+	default:
+		return fmt.Sprintf("bytes=%d-%d", start, end)
+	}
+}
+
+// cutRange extracts the requested range from a full 200 body
+func cutRange(resp *http.Response, start, end int64) ([]byte, ContentRange, error) {
+	var body []byte
+	var err error
+	if start >= 0 && end >= 0 {
+		body, err = io.ReadAll(io.LimitReader(resp.Body, end+1))
+	} else {
+		body, err = io.ReadAll(resp.Body)
+	}
+	if err != nil {
+		return nil, ContentRange{}, err
+	}
+
+	size := resp.ContentLength
+	if start < 0 || end < 0 {
+		size = int64(len(body))
+	}
+	from := start
+	if start < 0 {
+		from = int64(len(body)) + start
+		if from < 0 {
+			from = 0
+		}
+	}
+	if from >= int64(len(body)) {
+		return nil, ContentRange{}, ErrRangeNotSatisfiable
+	}
+	to := int64(len(body)) - 1
+	if end >= 0 && end < to {
+		to = end
+	}
+	return body[from : to+1], ContentRange{Start: from, End: to, Size: size}, nil
+}
@@ -0,0 +1,191 @@
+// Package ratelimit throttles requests on the client side before they reach
+// the wire, so third-party API limits are respected instead of hit.
+// it is a leaf package: httplib waits on a RequestLimiter set on NewClient.Limiter
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limiter blocks until one more request may be sent or ctx is done
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// RequestLimiter picks the limit for a request, e.g. per host or per endpoint
+type RequestLimiter interface {
+	Wait(ctx context.Context, req *http.Request) error
+}
+
+// TokenBucket allows Rate requests per second on average with bursts of up to
+// Burst; it starts full. use NewTokenBucket or set both fields before first use.
+// Rate must be positive; Wait fails otherwise
+type TokenBucket struct {
+	Rate  float64
+	Burst int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a full bucket refilling at rate tokens per second
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{Rate: rate, Burst: burst}
+}
+
+// Wait takes a token, sleeping until one is available
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		if !(b.Rate > 0) {
+			rate := b.Rate
+			b.mu.Unlock()
+			return fmt.Errorf("ratelimit: token bucket rate must be positive, got %v", rate)
+		}
+		now := time.Now()
+		burst := float64(b.Burst)
+		if burst < 1 {
+			burst = 1
+		}
+		if b.last.IsZero() {
+			b.tokens = burst
+		} else {
+			b.tokens += now.Sub(b.last).Seconds() * b.Rate
+			if b.tokens > burst {
+				b.tokens = burst
+			}
+		}
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) / b.Rate * float64(time.Second))
+		b.mu.Unlock()
+
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// LeakyBucket spaces requests at least Interval apart, queueing callers in
+// arrival order, for vendors that allow "one request per second" literally
+type LeakyBucket struct {
+	Interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewLeakyBucket returns a bucket letting one request through per interval
+func NewLeakyBucket(interval time.Duration) *LeakyBucket {
+	return &LeakyBucket{Interval: interval}
+}
+
+// Wait reserves the next slot and sleeps until it. a caller whose ctx ends
+// first hands its slot back if no one queued behind it; otherwise the slot
+// stays used so later callers keep their place and spacing
+func (b *LeakyBucket) Wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	prev := b.next
+	slot := prev
+	if slot.Before(now) {
+		slot = now
+	}
+	end := slot.Add(b.Interval)
+	b.next = end
+	b.mu.Unlock()
+
+	err := sleep(ctx, time.Until(slot))
+	if err != nil {
+		b.mu.Lock()
+		if b.next.Equal(end) {
+			b.next = prev
+		}
+		b.mu.Unlock()
+	}
+	return err
+}
+
+// PerHost keeps a separate limiter per request host, created with New on first use
+type PerHost struct {
+	New func() Limiter
+
+	mu    sync.Mutex
+	hosts map[string]Limiter
+}
+
+// Wait waits on the limiter of req's host
+func (p *PerHost) Wait(ctx context.Context, req *http.Request) error {
+	p.mu.Lock()
+	if p.hosts == nil {
+		p.hosts = make(map[string]Limiter)
+	}
+	l, ok := p.hosts[req.URL.Host]
+	if !ok {
+		l = p.New()
+		p.hosts[req.URL.Host] = l
+	}
+	p.mu.Unlock()
+	return l.Wait(ctx)
+}
+
+// ByPrefix limits endpoints by the longest matching host and path prefix,
+// e.g. "api.example.com/v1/search"; requests matching none use Default, or
+// are not limited when Default is nil
+type ByPrefix struct {
+	Limits  map[string]Limiter
+	Default Limiter
+}
+
+// Wait waits on the limiter matching req
+func (p ByPrefix) Wait(ctx context.Context, req *http.Request) error {
+	key := req.URL.Host + req.URL.Path
+	var match Limiter
+	longest := -1
+	for prefix, l := range p.Limits {
+		if len(prefix) > longest && strings.HasPrefix(key, prefix) {
+			match, longest = l, len(prefix)
+		}
+	}
+	if match == nil {
+		match = p.Default
+	}
+	if match == nil {
+		return nil
+	}
+	return match.Wait(ctx)
+}
+
+// All applies one limiter to every request
+type All struct {
+	Limiter
+}
+
+// Wait waits on the shared limiter
+func (a All) Wait(ctx context.Context, _ *http.Request) error {
+	return a.Limiter.Wait(ctx)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,218 @@
+package httplib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// Redaction lists the secrets masked in everything the package logs, and the
+// headers masked in archive records
+type Redaction struct {
+	// Headers are masked by name, case-insensitively
+	Headers []string
+	// QueryParams are masked in logged URLs, e.g. "api_key", and as fields of
+	// JSON and form bodies, case-insensitively
+	QueryParams []string
+	// Patterns mask every header, query parameter and body field whose name matches,
+	// e.g. regexp.MustCompile(`(?i)token|secret`)
+	Patterns []*regexp.Regexp
+}
+
+// DefaultRedaction is in effect until SetRedaction is called
+var DefaultRedaction = Redaction{
+	Headers:     DefaultArchiveRedact,
+	QueryParams: []string{"access_token", "api_key", "apikey", "client_secret", "key", "password", "signature", "sig", "token"},
+}
+
+// redactedValue replaces masked values
+const redactedValue = "REDACTED"
+
+// redactionHolder keeps atomic.Value storing one concrete type
+type redactionHolder struct{ r Redaction }
+
+var currentRedaction atomic.Value // redactionHolder
+
+// SetRedaction replaces what the package masks from now on
+func SetRedaction(r Redaction) {
+	currentRedaction.Store(redactionHolder{r})
+}
+
+func redaction() Redaction {
+	if h, ok := currentRedaction.Load().(redactionHolder); ok {
+		return h.r
+	}
+	return DefaultRedaction
+}
+
+// masks reports whether the header or query parameter names should be masked
+func (r Redaction) masks(name string, names []string, canonical func(string) string) bool {
+	for _, n := range names {
+		if canonical(n) == canonical(name) {
+			return true
+		}
+	}
+	for _, p := range r.Patterns {
+		if p.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactHeaders returns a copy of h with masked header values replaced
+func RedactHeaders(h http.Header) http.Header {
+	return redaction().header(h)
+}
+
+func (r Redaction) header(h http.Header) http.Header {
+	c := h.Clone()
+	for name, vs := range c {
+		if r.masks(name, r.Headers, http.CanonicalHeaderKey) {
+			masked := make([]string, len(vs))
+			for i := range masked {
+				masked[i] = redactedValue
+			}
+			c[name] = masked
+		}
+	}
+	return c
+}
+
+// RedactURL returns u as a string with its password and masked query values replaced
+func RedactURL(u *url.URL) string {
+	return redaction().url(u)
+}
+
+func (r Redaction) url(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	c := *u
+	if _, ok := c.User.Password(); ok {
+		c.User = url.UserPassword(c.User.Username(), redactedValue)
+	}
+	if c.RawQuery != "" {
+		q := c.Query()
+		changed := false
+		for name, vs := range q {
+			if r.masks(name, r.QueryParams, strings.ToLower) {
+				for i := range vs {
+					vs[i] = redactedValue
+				}
+				changed = true
+			}
+		}
+		if changed {
+			c.RawQuery = q.Encode()
+		}
+	}
+	return c.String()
+}
+
+// redactString is RedactURL for a URL that may not parse; unparsable ones are dropped
+func redactString(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redactedValue
+	}
+	return RedactURL(u)
+}
+
+// redactErr returns err for logging with the URL of a *url.Error redacted
+func redactErr(err error) error {
+	var uerr *url.Error
+	if !errors.As(err, &uerr) {
+		return err
+	}
+	return &url.Error{Op: uerr.Op, URL: redactString(uerr.URL), Err: uerr.Err}
+}
+
+// redactBody returns body with the values of masked fields replaced when it is
+// JSON or a form; other bodies are returned as is
+func redactBody(contentType string, body []byte) string {
+	return redaction().body(contentType, body)
+}
+
+func (r Redaction) body(contentType string, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == ContentTypeForm {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return string(body)
+		}
+		for name, vs := range form {
+			if r.masks(name, r.QueryParams, strings.ToLower) {
+				for i := range vs {
+					vs[i] = redactedValue
+				}
+			}
+		}
+		return form.Encode()
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if dec.Decode(&doc) != nil {
+		return r.maskJSONText(body)
+	}
+	if !r.maskJSON(doc) {
+		return string(body)
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if enc.Encode(doc) != nil {
+		return string(body)
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// jsonField matches a "name": value pair; the value may be cut off at the end
+var jsonField = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*:\s*)("(?:[^"\\]|\\.)*"?|[^\s,{}[\]]+)`)
+
+// maskJSONText masks matching fields of JSON that does not parse, e.g. a body
+// prefix cut off for a dump; bodies not starting like JSON are returned as is
+func (r Redaction) maskJSONText(body []byte) string {
+	text := string(body)
+	if t := strings.TrimSpace(text); t == "" || (t[0] != '{' && t[0] != '[') {
+		return text
+	}
+	return jsonField.ReplaceAllStringFunc(text, func(field string) string {
+		m := jsonField.FindStringSubmatch(field)
+		if !r.masks(m[1], r.QueryParams, strings.ToLower) {
+			return field
+		}
+		return `"` + m[1] + `"` + m[2] + `"` + redactedValue + `"`
+	})
+}
+
+// maskJSON masks the matching fields of doc in place and reports whether any did
+func (r Redaction) maskJSON(doc interface{}) bool {
+	changed := false
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for name, field := range v {
+			if r.masks(name, r.QueryParams, strings.ToLower) {
+				v[name] = redactedValue
+				changed = true
+			} else if r.maskJSON(field) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if r.maskJSON(item) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,241 @@
+package httplib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/clairmont32/httplib/internal/logging"
+)
+
+// RegionSelector routes requests to the fastest healthy of several regional
+// base URLs, measured by probing them periodically, e.g.
+//
+//	sel, err := NewRegionSelector("https://us.api.example.com", "https://eu.api.example.com")
+//	go sel.Run(ctx)
+//	c := NewClient{Timeout: 10 * time.Second, Middleware: []Middleware{sel.Middleware()}}
+//
+// until the first probe completes requests go to the first region
+type RegionSelector struct {
+	// ProbePath is requested on each region to measure it; empty probes "/"
+	ProbePath string
+	// Interval between probe rounds in Run; zero probes every 30s
+	Interval time.Duration
+	// Stickiness keeps the current region unless another is faster by this
+	// fraction, so traffic does not flap between similar regions; zero uses 0.2
+	Stickiness float64
+	// Probe measures one region, replacing the default GET of ProbePath.
+	// an error marks the region unhealthy
+	Probe func(ctx context.Context, base *url.URL) (time.Duration, error)
+
+	regions   []*url.URL
+	residency []string // data residency label per region, "" when unlabeled
+
+	mu      sync.Mutex
+	current int
+	pinned  int // -1 when not pinned
+	status  []RegionStatus
+}
+
+// RegionStatus is the latest probe result of one region
+type RegionStatus struct {
+	URL     string
+	Latency time.Duration
+	Healthy bool
+	Err     error
+	Probed  time.Time
+}
+
+// NewRegionSelector returns a selector over the base URLs in regions
+func NewRegionSelector(regions ...string) (*RegionSelector, error) {
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("region selector needs at least one region")
+	}
+	s := &RegionSelector{pinned: -1}
+	for _, r := range regions {
+		u, err := url.Parse(r)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("region %q needs a scheme and host", r)
+		}
+		s.regions = append(s.regions, u)
+		s.residency = append(s.residency, "")
+		// regions count as healthy until probed so the first one is used
+		s.status = append(s.status, RegionStatus{URL: r, Healthy: true})
+	}
+	return s, nil
+}
+
+// Run probes every region each Interval until ctx is done, starting at once
+func (s *RegionSelector) Run(ctx context.Context) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		s.ProbeNow(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// ProbeNow probes every region concurrently and updates the selection
+func (s *RegionSelector) ProbeNow(ctx context.Context) {
+	results := make([]RegionStatus, len(s.regions))
+	var wg sync.WaitGroup
+	for i, base := range s.regions {
+		wg.Add(1)
+		go func(i int, base *url.URL) {
+			defer wg.Done()
+			latency, err := s.probe(ctx, base)
+			results[i] = RegionStatus{URL: base.String(), Latency: latency, Healthy: err == nil, Err: err, Probed: time.Now()}
+		}(i, base)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		// probes cut short by shutdown say nothing about the regions
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = results
+	if next := s.choose(); next != s.current {
+		logging.Get().Info("switching region", "from", s.regions[s.current].String(), "to", s.regions[next].String())
+		s.current = next
+	}
+}
+
+// choose returns the region to use given the latest probes; callers hold mu
+func (s *RegionSelector) choose() int {
+	stickiness := s.Stickiness
+	if stickiness <= 0 {
+		stickiness = 0.2
+	}
+	best := -1
+	for i, st := range s.status {
+		if st.Healthy && (best < 0 || st.Latency < s.status[best].Latency) {
+			best = i
+		}
+	}
+	cur := s.status[s.current]
+	switch {
+	case best < 0:
+		// nothing is healthy; staying put beats guessing
+		return s.current
+	case !cur.Healthy:
+		return best
+	case float64(s.status[best].Latency) < float64(cur.Latency)*(1-stickiness):
+		return best
+	default:
+		return s.current
+	}
+}
+
+func (s *RegionSelector) probe(ctx context.Context, base *url.URL) (time.Duration, error) {
+	if s.Probe != nil {
+		return s.Probe(ctx, base)
+	}
+	u := *base
+	u.Path = s.ProbePath
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	latency := time.Since(start)
+	_ = DrainBody(resp)
+	if resp.StatusCode >= 500 {
+		return latency, fmt.Errorf("probe %s: %s", u.String(), resp.Status)
+	}
+	return latency, nil
+}
+
+// Current returns the base URL requests are routed to
+func (s *RegionSelector) Current() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.regions[s.active()].String()
+}
+
+// active is the pinned region or else the selected one; callers hold mu
+func (s *RegionSelector) active() int {
+	if s.pinned >= 0 {
+		return s.pinned
+	}
+	return s.current
+}
+
+// Pin routes all requests to region, one of the selector's base URLs, until
+// Unpin, regardless of probe results
+func (s *RegionSelector) Pin(region string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, u := range s.regions {
+		if u.String() == region {
+			s.pinned = i
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown region %q", region)
+}
+
+// Unpin returns to latency based selection
+func (s *RegionSelector) Unpin() {
+	s.mu.Lock()
+	s.pinned = -1
+	s.mu.Unlock()
+}
+
+// Status returns the latest probe result of every region in configuration order
+func (s *RegionSelector) Status() []RegionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RegionStatus(nil), s.status...)
+}
+
+// Middleware rewrites the scheme and host of every request to the current
+// region, or for requests with a WithResidency requirement the best region
+// allowed to receive them
+func (s *RegionSelector) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return regionTransport{next: next, s: s}
+	}
+}
+
+type regionTransport struct {
+	next http.RoundTripper
+	s    *RegionSelector
+}
+
+func (t regionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base, err := t.s.route(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r := req.Clone(req.Context())
+	r.URL.Scheme = base.Scheme
+	r.URL.Host = base.Host
+	r.Host = ""
+	return t.next.RoundTrip(r)
+}
@@ -0,0 +1,129 @@
+package httplib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotArchived is returned by ReplayTransport for requests with no archived response
+var ErrNotArchived = errors.New("no archived response")
+
+// ReplayTransport serves responses recorded by ArchiveTransport into a DirSink
+// instead of hitting the network, for air-gapped analysis and postmortems.
+// requests match records by method and URL, compared after RedactURL as
+// archived URLs are stored redacted; set it as NewClient.Transport to run a
+// client offline
+type ReplayTransport struct {
+	// At selects the most recent record at or before it; zero serves the latest
+	At time.Time
+
+	records map[string][]ArchiveRecord
+}
+
+// NewReplayTransport loads every record under dir, the Dir of a DirSink
+func NewReplayTransport(dir string) (*ReplayTransport, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "*.json*"))
+	if err != nil {
+		return nil, err
+	}
+
+	t := &ReplayTransport{records: make(map[string][]ArchiveRecord)}
+	for _, path := range paths {
+		rec, err := readArchiveRecord(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		// records from before redaction hold raw URLs; normalize them alike
+		key := replayKey(rec.Method, redactString(rec.URL))
+		t.records[key] = append(t.records[key], rec)
+	}
+	for _, recs := range t.records {
+		sort.SliceStable(recs, func(i, j int) bool { return recs[i].Time.Before(recs[j].Time) })
+	}
+	return t, nil
+}
+
+// RoundTrip returns the archived response for req
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	rec, ok := t.find(req.Method, RedactURL(req.URL))
+	if !ok {
+		return nil, fmt.Errorf("%w for %s %s", ErrNotArchived, req.Method, req.URL)
+	}
+
+	header := rec.ResponseHeader.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(rec.Status) + " " + http.StatusText(rec.Status),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(rec.Body)),
+		ContentLength: int64(len(rec.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *ReplayTransport) find(method, url string) (ArchiveRecord, bool) {
+	recs := t.records[replayKey(method, url)]
+	if len(recs) == 0 {
+		return ArchiveRecord{}, false
+	}
+	if t.At.IsZero() {
+		return recs[len(recs)-1], true
+	}
+	// first record after At; the one before it is the latest at or before At
+	i := sort.Search(len(recs), func(i int) bool { return recs[i].Time.After(t.At) })
+	if i == 0 {
+		return ArchiveRecord{}, false
+	}
+	return recs[i-1], true
+}
+
+func replayKey(method, url string) string {
+	return method + " " + url
+}
+
+// readArchiveRecord decodes a record file, decompressing it with the codec
+// named by the extension after .json
+func readArchiveRecord(path string) (ArchiveRecord, error) {
+	var rec ArchiveRecord
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rec, err
+	}
+	base := filepath.Base(path)
+	if ext := base[strings.Index(base, ".json")+len(".json"):]; ext != "" {
+		codec, ok := lookupCodec(strings.TrimPrefix(ext, "."))
+		if !ok {
+			return rec, fmt.Errorf("unknown codec %q", ext)
+		}
+		r, err := codec.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return rec, err
+		}
+		data, err = io.ReadAll(r)
+		_ = r.Close()
+		if err != nil {
+			return rec, err
+		}
+	}
+	err = json.Unmarshal(data, &rec)
+	return rec, err
+}
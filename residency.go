@@ -0,0 +1,86 @@
+package httplib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WithResidency returns a copy of req that a RegionSelector only routes to
+// regions labeled with one of allowed, e.g. WithResidency(req, "eu").
+// requests without a compliant region fail with a *ResidencyError
+func WithResidency(req *http.Request, allowed ...string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), residencyKey, allowed))
+}
+
+// RequestResidency returns the residency labels required by req, if any
+func RequestResidency(req *http.Request) []string {
+	allowed, _ := req.Context().Value(residencyKey).([]string)
+	return allowed
+}
+
+// ResidencyError is returned for a request whose residency requirement no
+// region satisfies. the request is not sent
+type ResidencyError struct {
+	Method  string
+	URL     string
+	Allowed []string
+}
+
+func (e *ResidencyError) Error() string {
+	return "no region satisfies data residency " + strings.Join(e.Allowed, " or ")
+}
+
+// SetResidency labels region, one of the selector's base URLs, with the data
+// residency it satisfies such as "eu"; an empty label removes it
+func (s *RegionSelector) SetResidency(region, label string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, u := range s.regions {
+		if u.String() == region {
+			s.residency[i] = label
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown region %q", region)
+}
+
+// route returns the base URL for req. a residency requirement overrides the
+// selection and pinning: the active region is kept if it complies, otherwise
+// the fastest healthy compliant region is used
+func (s *RegionSelector) route(req *http.Request) (*url.URL, error) {
+	allowed := RequestResidency(req)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	active := s.active()
+	if len(allowed) == 0 || s.complies(active, allowed) {
+		return s.regions[active], nil
+	}
+
+	best := -1
+	for i := range s.regions {
+		if !s.complies(i, allowed) {
+			continue
+		}
+		st := s.status[i]
+		if best < 0 || (st.Healthy && (!s.status[best].Healthy || st.Latency < s.status[best].Latency)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil, &ResidencyError{Method: req.Method, URL: req.URL.String(), Allowed: allowed}
+	}
+	return s.regions[best], nil
+}
+
+// complies reports whether region i carries one of allowed; callers hold mu
+func (s *RegionSelector) complies(i int, allowed []string) bool {
+	for _, label := range allowed {
+		if label != "" && s.residency[i] == label {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package httplib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Response is a fully read HTTP response with its timing.
+// unlike DoRequest it carries trailers and the body bytes, and unlike
+// DefaultRequest a non-2xx status is not an error until Err is called
+type Response struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Trailer    http.Header
+	Body       []byte
+
+	// Request is the request as sent, after profiles and live config applied
+	Request *http.Request
+
+	Timing Timing
+
+	// Operational holds rate limit, retry, cache and server timing headers
+	Operational OperationalHeaders
+}
+
+// Timing holds offsets from the start of a request and the durations of its
+// connection phases; phases that were not observed, such as DNS on a reused
+// connection or anything after a failure, are zero
+type Timing struct {
+	// Queued is how long the request waited for a connection, including dialing
+	Queued time.Duration
+	// Sent is when the request was fully written
+	Sent time.Duration
+	// FirstByte is when the first response byte arrived
+	FirstByte time.Duration
+	// Total is when the body was fully read
+	Total time.Duration
+
+	// DNS, Connect and TLS are how long the lookup, the TCP connect and the
+	// handshake of a new connection took
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// Reused is set when the request went out on a pooled connection
+	Reused bool
+}
+
+// StatusError is returned by Response.Err for non-2xx responses, and by
+// ProcessStatusCode when ErrorBody is enabled
+type StatusError struct {
+	StatusCode  int
+	Status      string
+	ContentType string
+	Body        []byte
+}
+
+func (e *StatusError) Error() string {
+	status := e.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	msg := "unexpected status " + status
+	if s := ErrorBody.snippet(e.ContentType, e.Body); s != "" {
+		msg += ": " + s
+	}
+	return msg
+}
+
+// ErrorBodyConfig controls the body excerpt status errors quote
+type ErrorBodyConfig struct {
+	// MaxBody is how many bytes of the body are quoted, after redaction;
+	// zero leaves bodies out of error messages
+	MaxBody int
+}
+
+// ErrorBody is applied to the message of every *StatusError and switches
+// ProcessStatusCode to return them for 4xx and 5xx responses, so on-call
+// engineers see what the upstream complained about. set it during initialization
+var ErrorBody ErrorBodyConfig
+
+// snippet returns the content type and the redacted, truncated body for an
+// error message, or "" when disabled or there is no body
+func (c ErrorBodyConfig) snippet(contentType string, body []byte) string {
+	if c.MaxBody <= 0 || len(bytes.TrimSpace(body)) == 0 {
+		return ""
+	}
+	text := strings.TrimSpace(redactBody(contentType, body))
+	truncated := len(text) > c.MaxBody
+	if truncated {
+		text = text[:c.MaxBody]
+	}
+	s := fmt.Sprintf("%q", text)
+	if truncated {
+		s += "..."
+	}
+	if contentType != "" {
+		s = contentType + " " + s
+	}
+	return s
+}
+
+// Do performs req, reads the body and returns it as a Response.
+// it goes through DoRequest, so profiles, live config and transformers apply
+func (c NewClient) Do(req *http.Request) (*Response, error) {
+	traced, timing := TraceTiming(req)
+	resp, _, err := c.DoRequest(traced)
+	if err != nil {
+		return nil, err
+	}
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		StatusCode:  resp.StatusCode,
+		Status:      resp.Status,
+		Header:      resp.Header,
+		Trailer:     resp.Trailer,
+		Body:        body,
+		Request:     resp.Request,
+		Timing:      timing(),
+		Operational: ParseOperationalHeaders(resp.Header, time.Now()),
+	}, nil
+}
+
+// Send is DefaultRequestWithContext returning the whole Response.
+// it performs the request through the default client
+func Send(ctx context.Context, req *FormRequest, headers []Headers) (*Response, error) {
+	r, err := req.FormRequestWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range headers {
+		h.AddHeader(r)
+	}
+	return sharedClient().Do(r)
+}
+
+// Err returns a *StatusError unless the status is 2xx
+func (r *Response) Err() error {
+	if r.StatusCode >= 200 && r.StatusCode < 300 {
+		return nil
+	}
+	return &StatusError{StatusCode: r.StatusCode, Status: r.Status, ContentType: r.Header.Get("Content-Type"), Body: r.Body}
+}
+
+// Kind classifies the body from its Content-Type, sniffing when it is missing
+func (r *Response) Kind() BodyKind {
+	kind, _ := ClassifyBody(r.Header.Get("Content-Type"), r.Body)
+	return kind
+}
+
+// Decode decodes a JSON or XML body into v
+func (r *Response) Decode(v interface{}) error {
+	_, err := DecodeBody(&http.Response{Header: r.Header}, r.Body, v)
+	return err
+}
+
+// Clone returns a deep copy of r with its own headers and body
+func (r *Response) Clone() *Response {
+	c := *r
+	c.Header = r.Header.Clone()
+	c.Trailer = r.Trailer.Clone()
+	c.Operational.ServerTiming = append([]ServerTiming(nil), r.Operational.ServerTiming...)
+	if r.Body != nil {
+		c.Body = append([]byte(nil), r.Body...)
+	}
+	return &c
+}
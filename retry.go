@@ -0,0 +1,168 @@
+package httplib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/clairmont32/httplib/observe"
+	"github.com/clairmont32/httplib/retry"
+)
+
+// WithRetryPolicy returns a copy of ctx that retries requests made with it under p,
+// overriding NewClient.Retry. use it with DefaultRequestWithContext for one-off calls
+func WithRetryPolicy(ctx context.Context, p retry.Policy) context.Context {
+	return context.WithValue(ctx, retryKey, p)
+}
+
+// retryPolicy returns the policy that applies to req, if retries are enabled for it
+func (c NewClient) retryPolicy(req *http.Request) (retry.Policy, bool) {
+	p, ok := req.Context().Value(retryKey).(retry.Policy)
+	if !ok {
+		if c.Retry == nil {
+			return retry.Policy{}, false
+		}
+		p = *c.Retry
+	}
+	if p.Attempts() < 2 || !c.flagEnabled(req.Context(), FlagRetries) || !p.AllowsRequest(req.Method, req.Header) {
+		return retry.Policy{}, false
+	}
+	return p, true
+}
+
+// send performs req with client, retrying transient failures under its retry policy.
+// a Retry-After header lengthens the wait before the next attempt.
+// every attempt publishes its own started and finished events
+func (c NewClient) send(client *http.Client, req *http.Request, timeout time.Duration) (*http.Response, error) {
+	policy, retrying := c.retryPolicy(req)
+	for attempt := 1; ; attempt++ {
+		resp, err := c.attempt(client, req, timeout)
+		if !retrying || attempt >= policy.Attempts() || !shouldRetry(policy, req, resp, err) {
+			return resp, err
+		}
+		next, ok := rewind(req)
+		if !ok {
+			return resp, err
+		}
+
+		delay := policy.Delay(attempt)
+		if resp != nil {
+			if after, ok := retry.ParseRetryAfter(resp.Header, time.Now()); ok {
+				if policy.MaxRetryAfter > 0 && after > policy.MaxRetryAfter {
+					return resp, err
+				}
+				if after > delay {
+					delay = after
+				}
+			}
+		}
+		scheduled := observe.RetryScheduled{Time: time.Now(), Method: req.Method, URL: req.URL.String(), Attempt: attempt, Delay: delay, Err: err}
+		if resp != nil {
+			scheduled.Status = resp.StatusCode
+			_ = DrainBody(resp)
+		}
+		c.Events.Publish(scheduled)
+		c.logger().Debug("retrying request", "method", req.Method, "url", RedactURL(req.URL), "delay", delay, "attempt", attempt)
+
+		if err := sleepCtx(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		req = next
+	}
+}
+
+// attempt performs req once and publishes its events.
+// it first waits on the Limiter, and with a Breaker set requests to an open
+// host fail without being sent. cancellations and timeouts report their Phase
+func (c NewClient) attempt(client *http.Client, req *http.Request, timeout time.Duration) (*http.Response, error) {
+	if c.Limiter != nil {
+		if err := c.Limiter.Wait(req.Context(), req); err != nil {
+			return nil, err
+		}
+	}
+	host := req.URL.Host
+	if c.Breaker != nil {
+		if err := c.Breaker.Allow(host); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL, err)
+		}
+	}
+
+	start := time.Now()
+	c.Events.Publish(observe.RequestStarted{Time: start, Method: req.Method, URL: req.URL.String()})
+	traced, timings := withTrace(req)
+	resp, err := client.Do(traced)
+	err = withPhase(req, timings, err)
+	if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+		// upgraded bodies must stay writable, so only plain bodies are wrapped
+		resp.Body = &phaseBody{ReadCloser: resp.Body, req: req, start: start}
+	}
+
+	finished := observe.RequestFinished{Time: time.Now(), Method: req.Method, URL: req.URL.String(), Err: err}
+	finished.Duration = finished.Time.Sub(start)
+	finished.Timeout = effectiveTimeout(req, start, timeout)
+	if resp != nil {
+		finished.Status = resp.StatusCode
+		finished.ServerTiming = observe.ParseServerTiming(resp.Header.Values("Server-Timing"))
+		if len(finished.ServerTiming) > 0 {
+			c.logger().Debug("server timing", "method", req.Method, "url", RedactURL(req.URL), "server_timing", formatServerTiming(finished.ServerTiming))
+		}
+	}
+	c.Events.Publish(finished)
+
+	if c.Breaker != nil {
+		// a caller giving up says nothing about the host, but a deadline or
+		// budget running out is the hanging upstream the breaker is for
+		if err != nil && errors.Is(req.Context().Err(), context.Canceled) {
+			c.Breaker.Release(host)
+			return resp, err
+		}
+		failed := err != nil || (resp != nil && resp.StatusCode >= 500)
+		if c.Breaker.Record(host, failed) {
+			c.Events.Publish(observe.BreakerOpened{Time: time.Now(), Host: host})
+			c.logger().Warn("circuit breaker opened", "host", host)
+		}
+	}
+	return resp, err
+}
+
+func shouldRetry(p retry.Policy, req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if err != nil {
+		var perr *PanicError
+		return !errors.As(err, &perr) && p.RetryableError(err)
+	}
+	return p.RetryableStatus(resp.StatusCode)
+}
+
+// rewind returns a copy of req with a fresh body, or false if the body cannot be replayed
+func rewind(req *http.Request) (*http.Request, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	r := req.Clone(req.Context())
+	r.Body = body
+	return r, true
+}
+
+// sleepCtx waits for d or until ctx is done
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
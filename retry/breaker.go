@@ -0,0 +1,148 @@
+package retry
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned for requests to a host whose breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// State is the state of the breaker for one host
+type State int
+
+// Breaker states
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker trips per host after Threshold consecutive failures and fast-fails
+// requests to it for Cooldown. after the cooldown a single probe request is let
+// through: success closes the breaker, failure opens it for another cooldown.
+// the zero value trips after 5 failures for 30s and is safe for concurrent use
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostBreaker
+}
+
+type hostBreaker struct {
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// Allow reports whether a request to host may be sent; it returns ErrCircuitOpen if not.
+// every allowed request must be followed by Record or Release
+func (b *Breaker) Allow(host string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := b.host(host)
+	switch h.state {
+	case Open:
+		if time.Since(h.openedAt) < b.cooldown() {
+			return ErrCircuitOpen
+		}
+		h.state = HalfOpen
+		h.probing = true
+		return nil
+	case HalfOpen:
+		if h.probing {
+			return ErrCircuitOpen
+		}
+		h.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record reports the outcome of a request Allow let through and returns
+// whether it opened the breaker
+func (b *Breaker) Record(host string, failed bool) (opened bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := b.host(host)
+	if !failed {
+		h.state = Closed
+		h.failures = 0
+		h.probing = false
+		return false
+	}
+
+	h.failures++
+	if h.state == HalfOpen || h.failures >= b.threshold() {
+		opened = h.state != Open
+		h.state = Open
+		h.openedAt = time.Now()
+		h.probing = false
+	}
+	return opened
+}
+
+// Release ends a request Allow let through without an outcome, e.g. one the
+// caller cancelled. a pending probe is freed, the state and failures are kept
+func (b *Breaker) Release(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.host(host).probing = false
+}
+
+// State returns the current state for host
+func (b *Breaker) State(host string) State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h, ok := b.hosts[host]
+	if !ok {
+		return Closed
+	}
+	if h.state == Open && time.Since(h.openedAt) >= b.cooldown() {
+		return HalfOpen
+	}
+	return h.state
+}
+
+func (b *Breaker) host(host string) *hostBreaker {
+	if b.hosts == nil {
+		b.hosts = make(map[string]*hostBreaker)
+	}
+	h, ok := b.hosts[host]
+	if !ok {
+		h = &hostBreaker{}
+		b.hosts[host] = h
+	}
+	return h
+}
+
+func (b *Breaker) threshold() int {
+	if b.Threshold <= 0 {
+		return 5
+	}
+	return b.Threshold
+}
+
+func (b *Breaker) cooldown() time.Duration {
+	if b.Cooldown <= 0 {
+		return 30 * time.Second
+	}
+	return b.Cooldown
+}
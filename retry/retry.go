@@ -0,0 +1,229 @@
+// Package retry defines when and how long to wait before a failed request is
+// tried again, and when to stop sending to a failing host at all.
+// it is a leaf package: httplib applies a Policy set on NewClient.Retry or per
+// call with httplib.WithRetryPolicy, and a Breaker set on NewClient.Breaker
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultStatuses are retried when Policy.Statuses is nil
+var DefaultStatuses = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// DefaultMethods are retried when Policy.Methods is nil; they are idempotent
+var DefaultMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodOptions,
+	http.MethodPut, http.MethodDelete, http.MethodTrace,
+}
+
+// DefaultKeyHeader carries the idempotency key of POST and PATCH requests
+const DefaultKeyHeader = "Idempotency-Key"
+
+// Source supplies jitter; *rand.Rand satisfies it but is not safe for
+// concurrent use, so share one through NewSource
+type Source interface {
+	Float64() float64
+}
+
+// NewSource returns a Source seeded with seed that is safe for concurrent use,
+// so retry timing is reproducible in tests
+func NewSource(seed int64) Source {
+	return &lockedSource{r: rand.New(rand.NewSource(seed))}
+}
+
+type lockedSource struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func (s *lockedSource) Float64() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Float64()
+}
+
+// globalSource is used when Policy.Rand is nil
+type globalSource struct{}
+
+func (globalSource) Float64() float64 { return rand.Float64() }
+
+// Policy describes how a request is retried
+type Policy struct {
+	// MaxAttempts counts the first try; 1 or less disables retries
+	MaxAttempts int
+
+	// Base is the wait before the first retry, doubled for each later one up to
+	// Cap; zero Cap lets it grow to the largest Duration
+	Base time.Duration
+	Cap  time.Duration
+
+	// Jitter is the fraction of each wait that is randomized, from 0 (none) to 1 (full jitter)
+	Jitter float64
+	// Rand supplies the jitter; nil uses math/rand
+	Rand Source
+
+	// Statuses are the response codes worth retrying; nil uses DefaultStatuses
+	Statuses []int
+
+	// Methods are the methods that may be retried; nil uses DefaultMethods.
+	// POST and PATCH listed here are only retried when the request carries KeyHeader
+	Methods []string
+	// KeyHeader is the idempotency key header; empty uses DefaultKeyHeader
+	KeyHeader string
+
+	// MaxRetryAfter caps how long a Retry-After header may delay a retry;
+	// responses asking for longer are returned instead. zero means no cap
+	MaxRetryAfter time.Duration
+}
+
+// Default returns 3 attempts waiting 200ms then 400ms, each with 50% jitter,
+// honoring Retry-After up to 30s
+func Default() Policy {
+	return Policy{MaxAttempts: 3, Base: 200 * time.Millisecond, Cap: 5 * time.Second, Jitter: 0.5, MaxRetryAfter: 30 * time.Second}
+}
+
+// Attempts returns the total number of tries, at least 1
+func (p Policy) Attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// Delay returns the wait before retry number n, starting at 1
+func (p Policy) Delay(n int) time.Duration {
+	d := p.Base
+	for i := 1; i < n && (p.Cap <= 0 || d < p.Cap); i++ {
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+	}
+	if p.Cap > 0 && d > p.Cap {
+		d = p.Cap
+	}
+	if p.Jitter <= 0 {
+		return d
+	}
+
+	jitter := p.Jitter
+	if jitter > 1 {
+		jitter = 1
+	}
+	src := p.Rand
+	if src == nil {
+		src = globalSource{}
+	}
+	fixed := time.Duration(float64(d) * (1 - jitter))
+	if fixed < 0 || fixed > d {
+		// float64 rounds the largest durations up past the int64 range
+		fixed = d
+	}
+	return fixed + time.Duration(src.Float64()*float64(d-fixed))
+}
+
+// AllowsRequest reports whether a request with method and header may be retried
+func (p Policy) AllowsRequest(method string, header http.Header) bool {
+	methods := p.Methods
+	if methods == nil {
+		methods = DefaultMethods
+	}
+	listed := false
+	for _, m := range methods {
+		if m == method {
+			listed = true
+			break
+		}
+	}
+	if !listed {
+		return false
+	}
+	if method == http.MethodPost || method == http.MethodPatch {
+		key := p.KeyHeader
+		if key == "" {
+			key = DefaultKeyHeader
+		}
+		return header.Get(key) != ""
+	}
+	return true
+}
+
+// RetryableStatus reports whether a response with code should be retried
+func (p Policy) RetryableStatus(code int) bool {
+	statuses := p.Statuses
+	if statuses == nil {
+		statuses = DefaultStatuses
+	}
+	for _, s := range statuses {
+		if s == code {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryableError reports whether err from performing a request is transient:
+// timeouts, resets, refused connections and connections closed mid-response.
+// cancellation and unknown hosts are not retried
+func (p Policy) RetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		err = uerr.Err
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var nerr net.Error
+	return errors.As(err, &nerr)
+}
+
+// ParseRetryAfter returns the wait a Retry-After header asks for, given either
+// as delay seconds or an HTTP-date relative to now. a date in the past is zero
+func ParseRetryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	v := strings.TrimSpace(h.Get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	at, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := at.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
@@ -0,0 +1,125 @@
+package httplib
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/clairmont32/httplib/internal/logging"
+	"github.com/clairmont32/httplib/retry"
+)
+
+// Runner sends Request on a cron Schedule and feeds every outcome to Handle.
+// runs never overlap: a run that outlasts the next activation skips it.
+// after a failed run the next one waits at least Backoff, doubling per
+// consecutive failure up to MaxBackoff
+type Runner struct {
+	Schedule Schedule
+	Request  FormRequest
+	Headers  []Headers
+
+	// Handle receives the body or error of every run
+	Handle func(body []byte, err error)
+
+	// Jitter delays each run by a random amount up to Jitter so fleets of agents
+	// don't hit the API on the same second
+	Jitter time.Duration
+	// Rand is the jitter source; nil uses math/rand. retry.NewSource gives a
+	// seeded one for reproducible timings that is safe to share
+	Rand retry.Source
+
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// Client performs the requests; nil uses the default client
+	Client *NewClient
+}
+
+// Run executes the schedule until ctx is done
+func (r *Runner) Run(ctx context.Context) error {
+	if r.Handle == nil {
+		return errors.New("Runner requires a Handle func")
+	}
+
+	failures := 0
+	var notBefore time.Time
+	for {
+		next := r.Schedule.Next(time.Now())
+		if next.IsZero() {
+			return errors.New("schedule never fires")
+		}
+		for next.Before(notBefore) {
+			next = r.Schedule.Next(next)
+		}
+		if r.Jitter > 0 {
+			next = next.Add(r.jitter())
+		}
+
+		t := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+
+		body, err := r.once(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		r.Handle(body, err)
+
+		if err == nil {
+			failures = 0
+			notBefore = time.Time{}
+			continue
+		}
+		failures++
+		if wait := r.backoff(failures); wait > 0 {
+			logging.Get().Debug("run failed", "failures", failures, "next_run_in", wait)
+			notBefore = time.Now().Add(wait)
+		}
+	}
+}
+
+func (r *Runner) once(ctx context.Context) ([]byte, error) {
+	page := r.Request.Clone()
+	req, err := page.FormRequestWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range r.Headers {
+		h.AddHeader(req)
+	}
+	resp, err := doWith(r.Client, req)
+	if err != nil {
+		return nil, err
+	}
+	return ProcessStatusCode(resp)
+}
+
+func (r *Runner) jitter() time.Duration {
+	if r.Rand != nil {
+		return time.Duration(r.Rand.Float64() * float64(r.Jitter))
+	}
+	return time.Duration(rand.Int63n(int64(r.Jitter)))
+}
+
+// backoff returns the minimum wait after n consecutive failures
+func (r *Runner) backoff(n int) time.Duration {
+	if r.Backoff <= 0 {
+		return 0
+	}
+	wait := r.Backoff
+	for i := 1; i < n; i++ {
+		wait *= 2
+		if r.MaxBackoff > 0 && wait >= r.MaxBackoff {
+			return r.MaxBackoff
+		}
+	}
+	if r.MaxBackoff > 0 && wait > r.MaxBackoff {
+		return r.MaxBackoff
+	}
+	return wait
+}
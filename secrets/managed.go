@@ -0,0 +1,211 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Token is a credential value and when it stops working; a zero Expiry never expires
+type Token struct {
+	Value  string
+	Expiry time.Time
+}
+
+// Managed keeps a credential fresh by refreshing it before it expires and
+// alerts through its callbacks before an expiry turns into an auth outage, e.g.
+//
+//	cred := &secrets.Managed{Fetch: login, OnExpiring: page, OnRefreshFailed: alert}
+//	go cred.Run(ctx)
+//	rt := secrets.HeaderTransport{Provider: cred, Header: "Authorization", Prefix: "Bearer "}
+type Managed struct {
+	// Fetch obtains a new token
+	Fetch func() (Token, error)
+	// RefreshBefore is how long before expiry a refresh starts; zero uses 1m
+	RefreshBefore time.Duration
+
+	// OnExpiring is called once per token when it is within RefreshBefore of
+	// expiring and could not be refreshed yet
+	OnExpiring func(remaining time.Duration)
+	// OnRefreshFailed is called on every failure from FailureThreshold
+	// consecutive failed refreshes on
+	OnRefreshFailed func(err error, failures int)
+	// FailureThreshold defaults to 3
+	FailureThreshold int
+	// the callbacks run while the credential is locked and must not call Value
+
+	mu       sync.Mutex
+	token    Token
+	has      bool
+	failures int
+	warned   bool // OnExpiring fired for the current token
+	lastErr  error
+	retryAt  time.Time     // no refresh is started before it after a failure
+	inflight chan struct{} // closed when the running refresh finishes
+}
+
+// ErrExpired is returned, wrapped, when the token expired and could not be refreshed
+var ErrExpired = errors.New("credential expired")
+
+// Get returns the current value, ignoring name so a Managed credential can
+// serve as a Provider
+func (m *Managed) Get(string) (string, error) {
+	return m.Value()
+}
+
+// Value returns a token that is not about to expire, refreshing it if needed.
+// while the old token is still valid it is returned at once and the refresh
+// runs in the background; only callers without a valid token wait for it.
+// after a failed refresh the next one waits out a backoff of up to a minute
+func (m *Managed) Value() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if m.has && !m.due(now) {
+		return m.token.Value, nil
+	}
+	done := m.inflight
+	if done == nil && !now.Before(m.retryAt) {
+		done = m.startRefresh()
+	}
+	if m.valid(now) {
+		return m.token.Value, nil
+	}
+	if done != nil {
+		m.mu.Unlock()
+		<-done
+		m.mu.Lock()
+		if m.valid(time.Now()) {
+			return m.token.Value, nil
+		}
+	}
+	switch {
+	case m.has && m.lastErr != nil:
+		return "", fmt.Errorf("%w: refresh failed: %v", ErrExpired, m.lastErr)
+	case m.has:
+		return "", ErrExpired
+	default:
+		return "", m.lastErr
+	}
+}
+
+// Run refreshes the token ahead of its expiry until ctx is done, retrying
+// failed refreshes with the same backoff as Value
+func (m *Managed) Run(ctx context.Context) {
+	for {
+		m.mu.Lock()
+		now := time.Now()
+		done := m.inflight
+		if done == nil && (!m.has || m.due(now)) && !now.Before(m.retryAt) {
+			done = m.startRefresh()
+		}
+		wait := m.untilDue(now)
+		m.mu.Unlock()
+
+		if done != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+			}
+			continue
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// startRefresh fetches a new token in the background, outside the lock, and
+// returns a channel closed once it is stored; callers hold mu and check that
+// no refresh is running
+func (m *Managed) startRefresh() chan struct{} {
+	done := make(chan struct{})
+	m.inflight = done
+	go func() {
+		tok, err := m.Fetch()
+		m.mu.Lock()
+		m.finishRefresh(tok, err, time.Now())
+		m.inflight = nil
+		m.mu.Unlock()
+		close(done)
+	}()
+	return done
+}
+
+// finishRefresh stores the outcome of a refresh and runs the callbacks; callers hold mu
+func (m *Managed) finishRefresh(tok Token, err error, now time.Time) {
+	if err == nil {
+		m.token, m.has, m.failures, m.warned = tok, true, 0, false
+		m.lastErr, m.retryAt = nil, time.Time{}
+		return
+	}
+	m.failures++
+	m.lastErr = err
+	m.retryAt = now.Add(refreshBackoff(m.failures))
+	threshold := m.FailureThreshold
+	if threshold <= 0 {
+		threshold = 3
+	}
+	if m.OnRefreshFailed != nil && m.failures >= threshold {
+		m.OnRefreshFailed(err, m.failures)
+	}
+	if m.has && !m.warned && m.OnExpiring != nil && !m.token.Expiry.IsZero() {
+		m.warned = true
+		m.OnExpiring(m.token.Expiry.Sub(now))
+	}
+}
+
+// refreshBackoff is the wait after the given number of consecutive failures,
+// doubling from a second up to a minute
+func refreshBackoff(failures int) time.Duration {
+	if failures > 6 {
+		return time.Minute
+	}
+	d := time.Second << uint(failures-1)
+	if d > time.Minute {
+		return time.Minute
+	}
+	return d
+}
+
+// valid reports whether there is a token that has not expired at now; callers hold mu
+func (m *Managed) valid(now time.Time) bool {
+	return m.has && (m.token.Expiry.IsZero() || now.Before(m.token.Expiry))
+}
+
+func (m *Managed) refreshBefore() time.Duration {
+	if m.RefreshBefore > 0 {
+		return m.RefreshBefore
+	}
+	return time.Minute
+}
+
+// due reports whether the token should be refreshed at now; callers hold mu
+func (m *Managed) due(now time.Time) bool {
+	return !m.token.Expiry.IsZero() && !now.Before(m.token.Expiry.Add(-m.refreshBefore()))
+}
+
+// untilDue is how long from now until the next refresh; callers hold mu
+func (m *Managed) untilDue(now time.Time) time.Duration {
+	if !m.has || m.due(now) {
+		if d := m.retryAt.Sub(now); d > 0 {
+			return d
+		}
+		return time.Second
+	}
+	if m.token.Expiry.IsZero() {
+		return time.Hour
+	}
+	if d := m.token.Expiry.Add(-m.refreshBefore()).Sub(now); d > 0 {
+		return d
+	}
+	return time.Second
+}
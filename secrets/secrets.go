@@ -0,0 +1,101 @@
+// Package secrets fetches credentials at use time from the environment, files
+// or Vault, so clients and auth transports are configured with secret names
+// instead of plaintext values
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned, wrapped, for a secret the provider does not have
+var ErrNotFound = errors.New("secret not found")
+
+// Provider returns the current value of the named secret
+type Provider interface {
+	Get(name string) (string, error)
+}
+
+// Env reads secrets from environment variables named Prefix + name
+type Env struct {
+	Prefix string
+}
+
+// Get returns the value of the variable; set but empty counts as present
+func (e Env) Get(name string) (string, error) {
+	v, ok := os.LookupEnv(e.Prefix + name)
+	if !ok {
+		return "", fmt.Errorf("env %s%s: %w", e.Prefix, name, ErrNotFound)
+	}
+	return v, nil
+}
+
+// File reads each secret from a file named after it in Dir, as mounted by
+// Kubernetes and Docker secrets. a trailing newline is dropped
+type File struct {
+	Dir string
+}
+
+// Get reads Dir/name; names cannot leave Dir
+func (f File) Get(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == ".." {
+		return "", fmt.Errorf("secret name %q must be a plain file name", name)
+	}
+	b, err := os.ReadFile(filepath.Join(f.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("file %s: %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// Cache keeps values of Provider for TTL so every request does not hit a
+// remote store. failures are not cached
+type Cache struct {
+	Provider Provider
+	TTL      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cached
+}
+
+type cached struct {
+	value   string
+	expires time.Time
+}
+
+// Get returns the cached value of name or fetches it
+func (c *Cache) Get(name string) (string, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[name]; ok && time.Now().Before(e.expires) {
+		c.mu.Unlock()
+		return e.value, nil
+	}
+	c.mu.Unlock()
+
+	v, err := c.Provider.Get(name)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]cached)
+	}
+	c.entries[name] = cached{value: v, expires: time.Now().Add(c.TTL)}
+	c.mu.Unlock()
+	return v, nil
+}
+
+// Forget drops the cached value of name, e.g. after the upstream rejected it
+func (c *Cache) Forget(name string) {
+	c.mu.Lock()
+	delete(c.entries, name)
+	c.mu.Unlock()
+}
@@ -0,0 +1,33 @@
+package secrets
+
+import "net/http"
+
+// HeaderTransport sets Header to Prefix plus the secret Name on every request,
+// fetching it from Provider at send time so rotated credentials are picked up,
+// e.g. HeaderTransport{Provider: vault, Name: "api#token", Header: "Authorization", Prefix: "Bearer "}.
+// a nil Next uses http.DefaultTransport
+type HeaderTransport struct {
+	Next     http.RoundTripper
+	Provider Provider
+	Name     string
+	Header   string
+	Prefix   string
+}
+
+// RoundTrip sends a copy of req carrying the credential
+func (t HeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	v, err := t.Provider.Get(t.Name)
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Header.Set(t.Header, t.Prefix+v)
+	return next.RoundTrip(r)
+}
@@ -0,0 +1,97 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Vault reads secrets from a HashiCorp Vault KV version 2 engine. names are
+// "<path>#<key>", e.g. "payments/stripe#api_key" reads key api_key of the
+// secret at payments/stripe
+type Vault struct {
+	// Addr is the Vault address; empty uses VAULT_ADDR
+	Addr string
+	// Token authenticates to Vault; empty uses VAULT_TOKEN at each call
+	Token string
+	// Mount is the KV engine mount; empty uses "secret"
+	Mount string
+	// Namespace is sent as X-Vault-Namespace when set
+	Namespace string
+	// Client performs the calls; nil uses a client with a 10s timeout
+	Client *http.Client
+}
+
+var vaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// Get fetches the latest version of the secret and returns the key of name
+func (v Vault) Get(name string) (string, error) {
+	path, key, ok := strings.Cut(name, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("vault secret %q must be <path>#<key>", name)
+	}
+	addr := firstNonEmpty(v.Addr, os.Getenv("VAULT_ADDR"))
+	if addr == "" {
+		return "", fmt.Errorf("vault address not set")
+	}
+	mount := firstNonEmpty(v.Mount, "secret")
+	u, err := url.Parse(strings.TrimRight(addr, "/") + "/v1/" + strings.Trim(mount, "/") + "/data/" + strings.TrimLeft(path, "/"))
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", firstNonEmpty(v.Token, os.Getenv("VAULT_TOKEN")))
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+	client := v.Client
+	if client == nil {
+		client = vaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", fmt.Errorf("vault %s: %w", path, ErrNotFound)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("vault %s: unexpected status %s", path, resp.Status)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("vault %s: %w", path, err)
+	}
+	val, ok := body.Data.Data[key]
+	if !ok {
+		return "", fmt.Errorf("vault %s key %s: %w", path, key, ErrNotFound)
+	}
+	s, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("vault %s key %s is not a string", path, key)
+	}
+	return s, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
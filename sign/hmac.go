@@ -0,0 +1,155 @@
+package sign
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultHeader carries the signature when HMAC.Header is empty
+const DefaultHeader = "Signature"
+
+// HMAC signs requests with HMAC-SHA256 over the method, path and query,
+// Date header and a SHA-256 of the body. the signature header reads
+//
+//	Signature: keyId="2024-06",algorithm="hmac-sha256",signature="<base64>"
+type HMAC struct {
+	Keys *KeySet
+	// Header names the signature header; empty uses DefaultHeader
+	Header string
+}
+
+// Sign sets the Date header if missing and signs req with the current key.
+// the body is read and replaced so it can still be sent
+func (h HMAC) Sign(req *http.Request) error {
+	key, err := h.Keys.Signing()
+	if err != nil {
+		return err
+	}
+	if req.Header.Get("Date") == "" {
+		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	digest, err := bodyDigest(req)
+	if err != nil {
+		return err
+	}
+	sig := mac(key.Secret, req, digest)
+	req.Header.Set(h.header(), fmt.Sprintf(`keyId="%s",algorithm="hmac-sha256",signature="%s"`, key.ID, sig))
+	return nil
+}
+
+// Verify checks the signature of a received req against any key valid now, so
+// requests signed with a key that was just rotated out still pass. maxSkew
+// bounds how far the Date header may be from the receiver's clock; zero skips the check
+func (h HMAC) Verify(req *http.Request, maxSkew time.Duration) error {
+	params := parseParams(req.Header.Get(h.header()))
+	id, sig := params["keyId"], params["signature"]
+	if id == "" || sig == "" {
+		return errors.New("missing or malformed signature")
+	}
+	if alg := params["algorithm"]; alg != "" && alg != "hmac-sha256" {
+		return fmt.Errorf("unsupported signature algorithm %q", alg)
+	}
+	if maxSkew > 0 {
+		date, err := http.ParseTime(req.Header.Get("Date"))
+		if err != nil {
+			return fmt.Errorf("signature date: %w", err)
+		}
+		if skew := time.Since(date); skew > maxSkew || skew < -maxSkew {
+			return fmt.Errorf("signature date is %s off", skew.Round(time.Second))
+		}
+	}
+	key, err := h.Keys.Verifying(id)
+	if err != nil {
+		return err
+	}
+	digest, err := bodyDigest(req)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(sig), []byte(mac(key.Secret, req, digest))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func (h HMAC) header() string {
+	if h.Header == "" {
+		return DefaultHeader
+	}
+	return h.Header
+}
+
+// mac signs the canonical form of req
+func mac(secret []byte, req *http.Request, digest []byte) string {
+	m := hmac.New(sha256.New, secret)
+	fmt.Fprintf(m, "%s\n%s\n%s\n", req.Method, req.URL.RequestURI(), req.Header.Get("Date"))
+	m.Write(digest)
+	return base64.StdEncoding.EncodeToString(m.Sum(nil))
+}
+
+// bodyDigest hashes the body and puts an unread copy back on req
+func bodyDigest(req *http.Request) ([]byte, error) {
+	sum := sha256.New()
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		sum.Write(body)
+	}
+	return sum.Sum(nil), nil
+}
+
+// parseParams reads comma separated key="value" pairs
+func parseParams(v string) map[string]string {
+	params := make(map[string]string)
+	for _, part := range strings.Split(v, ",") {
+		k, val, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			params[k] = strings.Trim(val, `"`)
+		}
+	}
+	return params
+}
+
+// Signer signs an outgoing request in place; HMAC and JWT implement it
+type Signer interface {
+	Sign(req *http.Request) error
+}
+
+// Transport signs every request with Signer before sending it through Next.
+// retries re-sign, so a rotation between attempts is picked up.
+// a nil Next uses http.DefaultTransport
+type Transport struct {
+	Next   http.RoundTripper
+	Signer Signer
+}
+
+// RoundTrip signs a copy of req and sends it
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := req.Clone(req.Context())
+	if err := t.Signer.Sign(r); err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+	return next.RoundTrip(r)
+}
@@ -0,0 +1,192 @@
+package sign
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultTTL is the lifetime of a JWT when JWT.TTL is zero
+const DefaultTTL = 5 * time.Minute
+
+// JWT signs requests with an HS256 bearer token whose kid header names the
+// key of Keys that signed it, so tokens keep verifying across a rotation the
+// same way HMAC signatures do
+//
+//	Authorization: Bearer <header>.<claims>.<signature>
+type JWT struct {
+	Keys *KeySet
+	// Issuer and Audience set the iss and aud claims; Verify checks them when set
+	Issuer   string
+	Audience string
+	// TTL bounds how long a token is accepted; zero uses DefaultTTL
+	TTL time.Duration
+	// Claims are added to every token; reserved claims set above win
+	Claims map[string]interface{}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid"`
+}
+
+// Token returns a signed token made with the current key
+func (j JWT) Token() (string, error) {
+	key, err := j.Keys.Signing()
+	if err != nil {
+		return "", err
+	}
+	now := j.Keys.now()
+	claims := make(map[string]interface{}, len(j.Claims)+4)
+	for k, v := range j.Claims {
+		claims[k] = v
+	}
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(j.ttl()).Unix()
+	if j.Issuer != "" {
+		claims["iss"] = j.Issuer
+	}
+	if j.Audience != "" {
+		claims["aud"] = j.Audience
+	}
+
+	header, err := json.Marshal(jwtHeader{Alg: "HS256", Typ: "JWT", Kid: key.ID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := b64(header) + "." + b64(payload)
+	return signed + "." + b64(jwtMAC(key.Secret, signed)), nil
+}
+
+// Sign sets the Authorization header of req to a fresh token
+func (j JWT) Sign(req *http.Request) error {
+	token, err := j.Token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Verify checks the bearer token of a received req against any key valid now.
+// maxSkew is the leeway given to exp and nbf for clock differences
+func (j JWT) Verify(req *http.Request, maxSkew time.Duration) error {
+	auth := req.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return errors.New("missing bearer token")
+	}
+	_, err := j.Parse(strings.TrimSpace(auth[7:]), maxSkew)
+	return err
+}
+
+// Parse verifies token and returns its claims. maxSkew is the leeway given to
+// exp and nbf for clock differences
+func (j JWT) Parse(token string, maxSkew time.Duration) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var h jwtHeader
+	if err := decodePart(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("token header: %w", err)
+	}
+	// only HS256 is accepted, never the alg the token asks for
+	if h.Alg != "HS256" {
+		return nil, fmt.Errorf("unsupported token algorithm %q", h.Alg)
+	}
+	if h.Kid == "" {
+		return nil, errors.New("token has no key ID")
+	}
+	key, err := j.Keys.Verifying(h.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("token signature: %w", err)
+	}
+	if !hmac.Equal(sig, jwtMAC(key.Secret, parts[0]+"."+parts[1])) {
+		return nil, errors.New("signature mismatch")
+	}
+
+	var claims map[string]interface{}
+	if err := decodePart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("token claims: %w", err)
+	}
+	if err := j.checkClaims(claims, maxSkew); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (j JWT) checkClaims(claims map[string]interface{}, maxSkew time.Duration) error {
+	now := j.Keys.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(maxSkew)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0).Add(-maxSkew)) {
+		return errors.New("token not valid yet")
+	}
+	if j.Issuer != "" && claims["iss"] != j.Issuer {
+		return fmt.Errorf("token issuer %v, want %q", claims["iss"], j.Issuer)
+	}
+	if j.Audience != "" && !hasAudience(claims["aud"], j.Audience) {
+		return fmt.Errorf("token audience %v, want %q", claims["aud"], j.Audience)
+	}
+	return nil
+}
+
+func (j JWT) ttl() time.Duration {
+	if j.TTL > 0 {
+		return j.TTL
+	}
+	return DefaultTTL
+}
+
+// hasAudience reports whether aud, a string or a list of them, holds want
+func hasAudience(aud interface{}, want string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == want
+	case []interface{}:
+		for _, a := range aud {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jwtMAC(secret []byte, signed string) []byte {
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte(signed))
+	return m.Sum(nil)
+}
+
+func decodePart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
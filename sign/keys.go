@@ -0,0 +1,119 @@
+// Package sign signs outgoing requests with HMAC signatures or HS256 JWTs
+// over keys that can be rotated without downtime. several keys are active at
+// once, each with a key ID and a validity window, so signatures made with the
+// previous key keep verifying while receivers pick up the next one
+package sign
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNoKey is returned when no key is valid for signing or for the key ID of a signature
+var ErrNoKey = errors.New("no valid signing key")
+
+// Key is one HMAC secret and the window it may be used in, by HMAC and JWT alike
+type Key struct {
+	ID     string
+	Secret []byte
+	// NotBefore schedules the key: it signs from then on, taking over from
+	// older keys. zero means it is valid from the start
+	NotBefore time.Time
+	// NotAfter retires the key for signing and verifying; zero never expires.
+	// overlap it with the next key's NotBefore by at least the longest clock
+	// skew and propagation delay expected
+	NotAfter time.Time
+}
+
+// valid reports whether k may be used at now
+func (k Key) valid(now time.Time) bool {
+	return !now.Before(k.NotBefore) && (k.NotAfter.IsZero() || now.Before(k.NotAfter))
+}
+
+// KeySet holds the keys of one signer. it is safe for concurrent use, so keys
+// can be added and removed while requests are signed
+type KeySet struct {
+	// Now returns the current time; nil uses time.Now
+	Now func() time.Time
+
+	mu   sync.RWMutex
+	keys []Key
+}
+
+// NewKeySet returns a KeySet holding keys
+func NewKeySet(keys ...Key) (*KeySet, error) {
+	s := &KeySet{}
+	for _, k := range keys {
+		if err := s.Add(k); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Add schedules k; its ID must be new and its secret non-empty
+func (s *KeySet) Add(k Key) error {
+	if k.ID == "" || len(k.Secret) == 0 {
+		return fmt.Errorf("key %q needs an ID and a secret", k.ID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, have := range s.keys {
+		if have.ID == k.ID {
+			return fmt.Errorf("duplicate key ID %q", k.ID)
+		}
+	}
+	s.keys = append(s.keys, k)
+	return nil
+}
+
+// Remove drops the key with id, e.g. once it leaked
+func (s *KeySet) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, k := range s.keys {
+		if k.ID == id {
+			s.keys = append(s.keys[:i:i], s.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+// Signing returns the key to sign with now: the valid key scheduled last
+func (s *KeySet) Signing() (Key, error) {
+	now := s.now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	best := -1
+	for i, k := range s.keys {
+		if k.valid(now) && (best < 0 || !k.NotBefore.Before(s.keys[best].NotBefore)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return Key{}, ErrNoKey
+	}
+	return s.keys[best], nil
+}
+
+// Verifying returns the key with id if it is valid now
+func (s *KeySet) Verifying(id string) (Key, error) {
+	now := s.now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, k := range s.keys {
+		if k.ID == id && k.valid(now) {
+			return k, nil
+		}
+	}
+	return Key{}, fmt.Errorf("key %q: %w", id, ErrNoKey)
+}
+
+func (s *KeySet) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,259 @@
+package sign
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testDate = "Mon, 03 Jun 2024 10:00:00 GMT"
+
+func newRequest(t *testing.T, body string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "https://api.example.com/v1/orders?b=2&a=1", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Date", testDate)
+	return req
+}
+
+// the signature covers the documented canonical form, computed here by hand
+func TestSignCanonicalForm(t *testing.T) {
+	keys, err := NewKeySet(Key{ID: "2024-06", Secret: []byte("s3cret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := newRequest(t, `{"qty":1}`)
+	if err := (HMAC{Keys: keys}).Sign(req); err != nil {
+		t.Fatal(err)
+	}
+
+	bodySum := sha256.Sum256([]byte(`{"qty":1}`))
+	m := hmac.New(sha256.New, []byte("s3cret"))
+	io.WriteString(m, "POST\n/v1/orders?b=2&a=1\n"+testDate+"\n")
+	m.Write(bodySum[:])
+	want := `keyId="2024-06",algorithm="hmac-sha256",signature="` + base64.StdEncoding.EncodeToString(m.Sum(nil)) + `"`
+	if got := req.Header.Get(DefaultHeader); got != want {
+		t.Fatalf("signature header\n got %s\nwant %s", got, want)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil || string(body) != `{"qty":1}` {
+		t.Fatalf("body after signing = %q, %v", body, err)
+	}
+}
+
+func TestVerifyRejectsChanges(t *testing.T) {
+	keys, err := NewKeySet(Key{ID: "k", Secret: []byte("s3cret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := HMAC{Keys: keys}
+	changes := map[string]func(*http.Request){
+		"body":   func(r *http.Request) { r.Body = io.NopCloser(strings.NewReader(`{"qty":9}`)) },
+		"method": func(r *http.Request) { r.Method = http.MethodPut },
+		"query":  func(r *http.Request) { r.URL.RawQuery = "b=2&a=2" },
+		"date":   func(r *http.Request) { r.Header.Set("Date", "Tue, 04 Jun 2024 10:00:00 GMT") },
+		"key id": func(r *http.Request) {
+			r.Header.Set(DefaultHeader, strings.Replace(r.Header.Get(DefaultHeader), `keyId="k"`, `keyId="other"`, 1))
+		},
+	}
+	for name, change := range changes {
+		t.Run(name, func(t *testing.T) {
+			req := newRequest(t, `{"qty":1}`)
+			if err := signer.Sign(req); err != nil {
+				t.Fatal(err)
+			}
+			if err := signer.Verify(req, 0); err != nil {
+				t.Fatalf("unchanged request: %v", err)
+			}
+			change(req)
+			if err := signer.Verify(req, 0); err == nil {
+				t.Fatal("Verify accepted a changed request")
+			}
+		})
+	}
+}
+
+func TestRotationOverlap(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	now := t0
+	oldKey := Key{ID: "old", Secret: []byte("old secret"), NotAfter: t0.Add(2 * time.Hour)}
+	newKey := Key{ID: "new", Secret: []byte("new secret"), NotBefore: t0.Add(time.Hour)}
+
+	sender, err := NewKeySet(oldKey, newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver, err := NewKeySet(oldKey, newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender.Now = func() time.Time { return now }
+	receiver.Now = func() time.Time { return now }
+
+	sign := func() *http.Request {
+		req := newRequest(t, "payload")
+		if err := (HMAC{Keys: sender}).Sign(req); err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+	keyID := func(req *http.Request) string {
+		return parseParams(req.Header.Get(DefaultHeader))["keyId"]
+	}
+
+	// before the new key is scheduled the old one signs
+	early := sign()
+	if id := keyID(early); id != "old" {
+		t.Fatalf("signed with %q before rotation, want old", id)
+	}
+
+	// in the overlap the new key signs and both verify
+	now = t0.Add(90 * time.Minute)
+	rotated := sign()
+	if id := keyID(rotated); id != "new" {
+		t.Fatalf("signed with %q during overlap, want new", id)
+	}
+	for _, req := range []*http.Request{early, rotated} {
+		if err := (HMAC{Keys: receiver}).Verify(req, 0); err != nil {
+			t.Errorf("%s signature during overlap: %v", keyID(req), err)
+		}
+	}
+
+	// once the old key retires its signatures stop verifying
+	now = t0.Add(3 * time.Hour)
+	if err := (HMAC{Keys: receiver}).Verify(early, 0); !errors.Is(err, ErrNoKey) {
+		t.Errorf("old signature after retirement: %v, want ErrNoKey", err)
+	}
+	if err := (HMAC{Keys: receiver}).Verify(rotated, 0); err != nil {
+		t.Errorf("new signature after retirement: %v", err)
+	}
+}
+
+func TestVerifyClockSkew(t *testing.T) {
+	keys, err := NewKeySet(Key{ID: "k", Secret: []byte("s3cret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := HMAC{Keys: keys}
+	req := newRequest(t, "")
+	req.Header.Set("Date", time.Now().Add(-10*time.Minute).UTC().Format(http.TimeFormat))
+	if err := signer.Sign(req); err != nil {
+		t.Fatal(err)
+	}
+	if err := signer.Verify(req, 5*time.Minute); err == nil {
+		t.Error("Verify accepted a date outside the skew")
+	}
+	if err := signer.Verify(req, 15*time.Minute); err != nil {
+		t.Errorf("Verify within skew: %v", err)
+	}
+}
+
+func TestJWTRotationOverlap(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	now := t0
+	keys, err := NewKeySet(
+		Key{ID: "old", Secret: []byte("old secret"), NotAfter: t0.Add(2 * time.Hour)},
+		Key{ID: "new", Secret: []byte("new secret"), NotBefore: t0.Add(time.Hour)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys.Now = func() time.Time { return now }
+	signer := JWT{Keys: keys, Issuer: "billing", Audience: "orders", TTL: 4 * time.Hour}
+
+	sign := func() *http.Request {
+		req := newRequest(t, "")
+		if err := signer.Sign(req); err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+	kid := func(req *http.Request) string {
+		var h jwtHeader
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if err := decodePart(strings.Split(token, ".")[0], &h); err != nil {
+			t.Fatal(err)
+		}
+		return h.Kid
+	}
+
+	early := sign()
+	if id := kid(early); id != "old" {
+		t.Fatalf("signed with %q before rotation, want old", id)
+	}
+	now = t0.Add(90 * time.Minute)
+	rotated := sign()
+	if id := kid(rotated); id != "new" {
+		t.Fatalf("signed with %q during overlap, want new", id)
+	}
+	for _, req := range []*http.Request{early, rotated} {
+		if err := signer.Verify(req, 0); err != nil {
+			t.Errorf("%s token during overlap: %v", kid(req), err)
+		}
+	}
+
+	now = t0.Add(3 * time.Hour)
+	if err := signer.Verify(early, 0); !errors.Is(err, ErrNoKey) {
+		t.Errorf("old token after retirement: %v, want ErrNoKey", err)
+	}
+	if err := signer.Verify(rotated, 0); err != nil {
+		t.Errorf("new token after retirement: %v", err)
+	}
+}
+
+func TestJWTRejects(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	keys, err := NewKeySet(Key{ID: "k", Secret: []byte("s3cret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys.Now = func() time.Time { return now }
+	signer := JWT{Keys: keys, Audience: "orders"}
+	token, err := signer.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+
+	tests := map[string]struct {
+		token  string
+		verify JWT
+		at     time.Time
+	}{
+		"expired":        {token: token, verify: signer, at: now.Add(DefaultTTL + time.Second)},
+		"other audience": {token: token, verify: JWT{Keys: keys, Audience: "users"}, at: now},
+		"alg none":       {token: b64([]byte(`{"alg":"none","kid":"k"}`)) + "." + parts[1] + ".", verify: signer, at: now},
+		"changed claims": {token: parts[0] + "." + b64([]byte(`{"exp":9999999999}`)) + "." + parts[2], verify: signer, at: now},
+		"unknown kid":    {token: b64([]byte(`{"alg":"HS256","kid":"x"}`)) + "." + parts[1] + "." + parts[2], verify: signer, at: now},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			now = tt.at
+			if _, err := tt.verify.Parse(tt.token, 0); err == nil {
+				t.Fatal("Parse accepted the token")
+			}
+		})
+	}
+
+	now = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	claims, err := signer.Parse(token, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["aud"] != "orders" {
+		t.Errorf("aud = %v, want orders", claims["aud"])
+	}
+	now = now.Add(DefaultTTL + time.Second)
+	if _, err := signer.Parse(token, time.Minute); err != nil {
+		t.Errorf("Parse within skew: %v", err)
+	}
+}
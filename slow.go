@@ -0,0 +1,104 @@
+package httplib
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/clairmont32/httplib/observe"
+)
+
+// WithSlowRequestThreshold wraps next so any request taking longer than d, from
+// sending until the body is read or closed, is logged at Warn with its metadata
+// and httptrace phase breakdown. it logs regardless of the debug level, so it
+// gives low-noise production diagnostics, through the Logger of the client the
+// request is sent with. a nil next uses http.DefaultTransport
+//
+//	c := NewClient{Transport: WithSlowRequestThreshold(nil, 2*time.Second), Timeout: 10 * time.Second}
+func WithSlowRequestThreshold(next http.RoundTripper, d time.Duration) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return slowRequests{next: next, threshold: d}
+}
+
+type slowRequests struct {
+	next      http.RoundTripper
+	threshold time.Duration
+}
+
+// RoundTrip times req and reports it once it finished past the threshold
+func (s slowRequests) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	traced, timings := withTrace(req)
+	resp, err := s.next.RoundTrip(traced)
+	if err != nil {
+		s.report(req, nil, err, time.Since(start), timings)
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		// upgraded bodies must stay writable, so the request ends here
+		s.report(req, resp, nil, time.Since(start), timings)
+		return resp, nil
+	}
+
+	resp.Body = &slowBody{ReadCloser: resp.Body, done: func(bodyErr error) {
+		s.report(req, resp, bodyErr, time.Since(start), timings)
+	}}
+	return resp, nil
+}
+
+func (s slowRequests) report(req *http.Request, resp *http.Response, err error, elapsed time.Duration, timings *traceTimings) {
+	if elapsed < s.threshold {
+		return
+	}
+	t := timings.snapshot()
+	kv := []interface{}{
+		"method", req.Method,
+		"url", RedactURL(req.URL),
+		"duration", elapsed,
+		"threshold", s.threshold,
+		"dns", t.DNS,
+		"connect", t.Connect,
+		"tls", t.TLS,
+		"first_byte", t.FirstByte,
+		"reused_conn", t.Reused,
+	}
+	if resp != nil {
+		kv = append(kv, "status", resp.StatusCode, "bytes", resp.ContentLength)
+		if st := observe.ParseServerTiming(resp.Header.Values("Server-Timing")); len(st) > 0 {
+			kv = append(kv, "server_timing", formatServerTiming(st))
+		}
+	}
+	if tags := RequestTags(req); len(tags) > 0 {
+		kv = append(kv, "tags", tags)
+	}
+	if err != nil {
+		kv = append(kv, "error", redactErr(err))
+	}
+	requestLogger(req).Warn("slow HTTP request", kv...)
+}
+
+// slowBody calls done once, when the body reaches EOF, fails or is closed
+type slowBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func(err error)
+}
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(func() { b.done(nil) })
+	} else if err != nil {
+		b.once.Do(func() { b.done(err) })
+	}
+	return n, err
+}
+
+func (b *slowBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(nil) })
+	return err
+}
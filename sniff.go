@@ -0,0 +1,94 @@
+package httplib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// BodyKind is the broad format of a response body
+type BodyKind string
+
+// Body kinds reported by ClassifyBody
+const (
+	KindJSON   BodyKind = "json"
+	KindXML    BodyKind = "xml"
+	KindHTML   BodyKind = "html"
+	KindText   BodyKind = "text"
+	KindBinary BodyKind = "binary"
+)
+
+// sniffLen matches the amount http.DetectContentType looks at
+const sniffLen = 512
+
+// ClassifyBody returns the kind of body from its Content-Type.
+// when the header is missing or application/octet-stream the first 512 bytes
+// are sniffed instead, since many APIs send JSON without declaring it.
+// sniffed reports whether the result came from the body rather than the header
+func ClassifyBody(contentType string, body []byte) (kind BodyKind, sniffed bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "" || mediaType == "application/octet-stream" {
+		return sniffBody(body), true
+	}
+	return kindOf(mediaType), false
+}
+
+// DecodeBody decodes body into v with the decoder matching its kind
+// and returns the kind it used. only JSON and XML bodies can be decoded
+func DecodeBody(resp *http.Response, body []byte, v interface{}) (BodyKind, error) {
+	kind, _ := ClassifyBody(resp.Header.Get("Content-Type"), body)
+	switch kind {
+	case KindJSON:
+		return kind, json.Unmarshal(body, v)
+	case KindXML:
+		return kind, DecodeXML(resp.Header.Get("Content-Type"), body, v)
+	default:
+		return kind, fmt.Errorf("cannot decode %s body", kind)
+	}
+}
+
+func kindOf(mediaType string) BodyKind {
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return KindJSON
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return KindXML
+	case mediaType == "text/html":
+		return KindHTML
+	case strings.HasPrefix(mediaType, "text/"):
+		return KindText
+	default:
+		return KindBinary
+	}
+}
+
+// sniffBody classifies a body without a usable Content-Type
+func sniffBody(body []byte) BodyKind {
+	if len(body) > sniffLen {
+		body = body[:sniffLen]
+	}
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if len(trimmed) == 0 {
+		return KindText
+	}
+
+	switch trimmed[0] {
+	case '{', '[':
+		return KindJSON
+	}
+
+	detected, _, _ := mime.ParseMediaType(http.DetectContentType(body))
+	switch {
+	case detected == "text/html":
+		return KindHTML
+	case detected == "text/xml" || bytes.HasPrefix(trimmed, []byte("<")):
+		return KindXML
+	case strings.HasPrefix(detected, "text/"):
+		return KindText
+	default:
+		return KindBinary
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package httplib
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func setTrafficClass(string, uintptr, int) error {
+	return fmt.Errorf("DSCP marking is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,17 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package httplib
+
+import (
+	"strings"
+	"syscall"
+)
+
+// setTrafficClass sets the TOS byte for IPv4 or the traffic class for IPv6 sockets
+func setTrafficClass(network string, fd uintptr, tos int) error {
+	if strings.HasSuffix(network, "6") {
+		return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, tos)
+	}
+	return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS, tos)
+}
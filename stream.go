@@ -0,0 +1,140 @@
+package httplib
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// streamBuffer is how many decoded elements StreamDecode holds ahead of the consumer
+const streamBuffer = 64
+
+// StreamDecode decodes a JSON array or NDJSON response body element by element
+// into the returned channel, so records are processed as they arrive. decoding
+// pauses while the channel is full. the error channel receives at most one
+// error and is closed with the element channel once the body is consumed.
+// cancelling the request context stops decoding; the body is always closed.
+// a failure after the first byte is a *StreamError telling where to resume
+//
+//	items, errc := StreamDecode[Item](resp)
+//	for it := range items {
+//		...
+//	}
+//	if err := <-errc; err != nil {
+//		...
+//	}
+func StreamDecode[T any](resp *http.Response) (<-chan T, <-chan error) {
+	out := make(chan T, streamBuffer)
+	errc := make(chan error, 1)
+	ctx := context.Background()
+	if resp.Request != nil {
+		ctx = resp.Request.Context()
+	}
+	go func() {
+		defer close(errc)
+		defer close(out)
+		defer resp.Body.Close()
+		if err := streamDecode(ctx, resp.Body, out); err != nil {
+			errc <- err
+		}
+	}()
+	return out, errc
+}
+
+// StreamError reports where a StreamDecode stopped so an export can resume
+// after the last delivered element instead of restarting
+type StreamError struct {
+	// Delivered is how many elements were sent on the channel; the last
+	// delivered one has index Delivered-1
+	Delivered int
+	// Offset is the body byte offset just past the last delivered element,
+	// e.g. for a Range request on NDJSON exports
+	Offset int64
+	// Cursor is the StreamCursor of the last delivered element, for element
+	// types implementing it
+	Cursor string
+	Err    error
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("stream stopped after %d elements (offset %d): %v", e.Delivered, e.Offset, e.Err)
+}
+
+func (e *StreamError) Unwrap() error { return e.Err }
+
+// StreamCursor is implemented by element types that carry a resume cursor,
+// e.g. an ID or a page token, which StreamError then reports
+type StreamCursor interface {
+	StreamCursor() string
+}
+
+func streamDecode[T any](ctx context.Context, body io.Reader, out chan<- T) error {
+	r := bufio.NewReader(body)
+	first, skipped, err := peekNonSpace(r)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(r)
+	state := &StreamError{}
+	fail := func(err error) error {
+		state.Err = err
+		return state
+	}
+	array := first == '['
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return fail(err)
+		}
+	}
+	for {
+		if array && !dec.More() {
+			if _, err := dec.Token(); err != nil {
+				return fail(err)
+			}
+			return nil
+		}
+		var v T
+		if err := dec.Decode(&v); err != nil {
+			if err == io.EOF && !array {
+				return nil
+			}
+			return fail(fmt.Errorf("element %d: %w", state.Delivered, err))
+		}
+		select {
+		case out <- v:
+		case <-ctx.Done():
+			return fail(ctx.Err())
+		}
+		state.Delivered++
+		// the decoder counts from after the leading whitespace
+		state.Offset = skipped + dec.InputOffset()
+		if c, ok := interface{}(v).(StreamCursor); ok {
+			state.Cursor = c.StreamCursor()
+		}
+	}
+}
+
+// peekNonSpace returns the first byte that is not JSON whitespace without
+// consuming it, and how many whitespace bytes were consumed before it
+func peekNonSpace(r *bufio.Reader) (byte, int64, error) {
+	var skipped int64
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, skipped, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			skipped++
+			continue
+		}
+		return b, skipped, r.UnreadByte()
+	}
+}
@@ -0,0 +1,33 @@
+package httplib
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithTags returns a copy of req labelled with tags such as "bulk" or a job ID,
+// which schedulers and other client features can act on. tags already on req are kept
+func WithTags(req *http.Request, tags ...string) *http.Request {
+	all := append(append([]string(nil), RequestTags(req)...), tags...)
+	return req.WithContext(context.WithValue(req.Context(), tagsKey, all))
+}
+
+// RequestTags returns the tags set on req with WithTags
+func RequestTags(req *http.Request) []string {
+	tags, _ := req.Context().Value(tagsKey).([]string)
+	return tags
+}
+
+// hasTag reports whether req carries tag
+func hasTag(req *http.Request, tag string) bool {
+	return containsTag(RequestTags(req), tag)
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package httplib
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"text/template"
+)
+
+// PayloadTemplate renders request bodies from a text/template so repetitive
+// payloads are not built with Sprintf. values are inserted verbatim unless
+// piped through the json or xml functions, which encode them for the body format:
+//
+//	{"hostname": {{json .Host}}}
+//	<name>{{xml .Name}}</name>
+//
+// missing keys in the data are an error rather than "<no value>"
+type PayloadTemplate struct {
+	t *template.Template
+}
+
+// templateFuncs encode values safely for the common body formats
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"xml": func(v interface{}) (string, error) {
+		var buf bytes.Buffer
+		err := xml.EscapeText(&buf, []byte(fmt.Sprint(v)))
+		return buf.String(), err
+	},
+}
+
+// ParsePayloadTemplate parses text once so it can be rendered for many requests
+func ParsePayloadTemplate(name, text string) (*PayloadTemplate, error) {
+	t, err := template.New(name).Option("missingkey=error").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &PayloadTemplate{t: t}, nil
+}
+
+// MustParsePayloadTemplate is ParsePayloadTemplate for package level templates; it panics on error
+func MustParsePayloadTemplate(name, text string) *PayloadTemplate {
+	p, err := ParsePayloadTemplate(name, text)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Render executes the template with data and returns the body
+func (p *PayloadTemplate) Render(data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := p.t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
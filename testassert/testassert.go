@@ -0,0 +1,140 @@
+// Package testassert provides assertion helpers for table-driven tests
+// that exercise code built on httplib.
+// each helper reports a readable failure through testing.TB and
+// returns false so callers can stop early if needed
+package testassert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/clairmont32/httplib"
+)
+
+// AssertStatus fails the test if the response status code is not want
+func AssertStatus(t testing.TB, resp *http.Response, want int) bool {
+	t.Helper()
+	if resp == nil {
+		t.Errorf("status: got nil response, want %d %s", want, http.StatusText(want))
+		return false
+	}
+	if resp.StatusCode != want {
+		t.Errorf("status: got %d %s, want %d %s\nbody: %s",
+			resp.StatusCode, http.StatusText(resp.StatusCode), want, http.StatusText(want), snippet(resp))
+		return false
+	}
+	return true
+}
+
+// AssertHeader fails the test if the response header key does not equal want
+// multiple values for the same key are joined with ", "
+func AssertHeader(t testing.TB, resp *http.Response, key, want string) bool {
+	t.Helper()
+	if resp == nil {
+		t.Errorf("header %q: got nil response, want %q", key, want)
+		return false
+	}
+	values, ok := resp.Header[http.CanonicalHeaderKey(key)]
+	if !ok {
+		t.Errorf("header %q: missing, want %q\nheaders: %v", key, want, resp.Header)
+		return false
+	}
+	if got := strings.Join(values, ", "); got != want {
+		t.Errorf("header %q: got %q, want %q", key, got, want)
+		return false
+	}
+	return true
+}
+
+// AssertJSONPath fails the test if the value at path in the JSON response body
+// does not equal want. path uses the httplib.Extract syntax, e.g. "data.items[0].id"
+// want is compared after a JSON round trip so 1 and 1.0 are equal
+func AssertJSONPath(t testing.TB, resp *http.Response, path string, want interface{}) bool {
+	t.Helper()
+	if resp == nil {
+		t.Errorf("json %q: got nil response", path)
+		return false
+	}
+	body, err := peekBody(resp)
+	if err != nil {
+		t.Errorf("json %q: reading body: %v", path, err)
+		return false
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Errorf("json %q: body is not valid JSON: %v\nbody: %s", path, err, truncate(body))
+		return false
+	}
+
+	got, err := httplib.LookupJSON(doc, path)
+	if err != nil {
+		t.Errorf("json %q: %v\nbody: %s", path, err, truncate(body))
+		return false
+	}
+
+	normalized, err := normalize(want)
+	if err != nil {
+		t.Errorf("json %q: want value cannot be encoded: %v", path, err)
+		return false
+	}
+	if !reflect.DeepEqual(got, normalized) {
+		t.Errorf("json %q: got %s, want %s", path, encode(got), encode(normalized))
+		return false
+	}
+	return true
+}
+
+// peekBody reads the body and replaces it so later assertions can read it again
+func peekBody(resp *http.Response) ([]byte, error) {
+	if resp.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return body, err
+}
+
+// normalize round trips v through JSON so it compares equal to decoded values
+func normalize(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(b, &out)
+	return out, err
+}
+
+func encode(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// maxSnippet caps how much of a body is printed in failure messages
+const maxSnippet = 512
+
+func truncate(b []byte) string {
+	if len(b) > maxSnippet {
+		return string(b[:maxSnippet]) + "..."
+	}
+	return string(b)
+}
+
+func snippet(resp *http.Response) string {
+	body, err := peekBody(resp)
+	if err != nil {
+		return fmt.Sprintf("<error reading body: %v>", err)
+	}
+	return truncate(body)
+}
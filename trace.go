@@ -0,0 +1,112 @@
+package httplib
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// traceTimings collects the phase durations of one request through httptrace
+type traceTimings struct {
+	mu sync.Mutex
+	tracePhases
+}
+
+// tracePhases are the collected timestamps and durations. phases that did not
+// happen, e.g. DNS on a reused connection, stay zero
+type tracePhases struct {
+	start        time.Time
+	dnsStart     time.Time
+	DNS          time.Duration
+	connectStart time.Time
+	Connect      time.Duration
+	tlsStart     time.Time
+	TLS          time.Duration
+	Reused       bool
+	gotConn      time.Time
+	wroteRequest time.Time
+	FirstByte    time.Duration
+}
+
+// withTrace returns a copy of req reporting into a new traceTimings
+func withTrace(req *http.Request) (*http.Request, *traceTimings) {
+	t := &traceTimings{tracePhases: tracePhases{start: time.Now()}}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.since(t.dnsStart, &t.DNS) },
+		ConnectStart: func(string, string) {
+			t.mark(&t.connectStart)
+		},
+		ConnectDone: func(string, string, error) { t.since(t.connectStart, &t.Connect) },
+		TLSHandshakeStart: func() {
+			t.mark(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) { t.since(t.tlsStart, &t.TLS) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.Reused = info.Reused
+			t.gotConn = time.Now()
+			t.mu.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.since(t.start, &t.FirstByte) },
+	}
+	ctx := httptrace.WithClientTrace(req.Context(), trace)
+	return req.WithContext(ctx), t
+}
+
+func (t *traceTimings) mark(at *time.Time) {
+	t.mu.Lock()
+	*at = time.Now()
+	t.mu.Unlock()
+}
+
+func (t *traceTimings) since(from time.Time, d *time.Duration) {
+	t.mu.Lock()
+	*d = time.Since(from)
+	t.mu.Unlock()
+}
+
+// snapshot returns a copy that is safe to read while the request continues
+func (t *traceTimings) snapshot() tracePhases {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tracePhases
+}
+
+// TraceTiming returns a copy of req recording its timing breakdown and a func
+// returning what was recorded so far, with Total as the time until the call.
+// use it with DoRequest to tell a slow network from a slow upstream:
+//
+//	req, timing := TraceTiming(req)
+//	resp, _, err := c.DoRequest(req)
+//	t := timing() // t.DNS, t.Connect, t.TLS, t.FirstByte
+//
+// for retried requests the phases are those of the last attempt, while the
+// offsets count from the first
+func TraceTiming(req *http.Request) (*http.Request, func() Timing) {
+	traced, t := withTrace(req)
+	return traced, func() Timing {
+		p := t.snapshot()
+		return Timing{
+			Queued:    p.offset(p.gotConn),
+			Sent:      p.offset(p.wroteRequest),
+			FirstByte: p.FirstByte,
+			Total:     time.Since(p.start),
+			DNS:       p.DNS,
+			Connect:   p.Connect,
+			TLS:       p.TLS,
+			Reused:    p.Reused,
+		}
+	}
+}
+
+// offset is the time from the start of the request to at, or zero if at never happened
+func (p tracePhases) offset(at time.Time) time.Duration {
+	if at.IsZero() {
+		return 0
+	}
+	return at.Sub(p.start)
+}
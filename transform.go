@@ -0,0 +1,65 @@
+package httplib
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Transformer rewrites a response body before it is returned, e.g. to decrypt
+// or unwrap vendor payloads. chains run in order, so decompress, decrypt and
+// decode steps can be composed per client or per profile
+type Transformer func(body []byte, header http.Header) ([]byte, error)
+
+// transform buffers the body of resp and runs it through transformers
+func transform(resp *http.Response, transformers []Transformer) error {
+	if len(transformers) == 0 || resp.Body == nil {
+		return nil
+	}
+	body, err := readBody(resp)
+	if err != nil {
+		return err
+	}
+	for i, t := range transformers {
+		if body, err = runTransformer(t, body, resp.Header); err != nil {
+			return fmt.Errorf("response transformer %d: %w", i, err)
+		}
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
+// runTransformer calls t, converting a panic into a *PanicError
+func runTransformer(t Transformer, body []byte, header http.Header) (out []byte, err error) {
+	defer recoverInto(&err)
+	return t(body, header)
+}
+
+// Decompress returns a Transformer decoding bodies with the registered codec name,
+// for payloads compressed inside the response rather than via Content-Encoding
+func Decompress(name string) Transformer {
+	return func(body []byte, _ http.Header) ([]byte, error) {
+		codec, ok := lookupCodec(name)
+		if !ok {
+			return nil, fmt.Errorf("no codec registered for %q", name)
+		}
+		r, err := codec.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+}
+
+// Base64Decode is a Transformer for bodies sent as standard base64
+func Base64Decode(body []byte, _ http.Header) ([]byte, error) {
+	out := make([]byte, base64.StdEncoding.DecodedLen(len(body)))
+	n, err := base64.StdEncoding.Decode(out, bytes.TrimSpace(body))
+	return out[:n], err
+}
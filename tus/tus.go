@@ -0,0 +1,307 @@
+// Package tus uploads files with the tus resumable upload protocol 1.0.0
+// (https://tus.io/protocols/resumable-upload), so large uploads over
+// unreliable links continue from the last offset the server confirmed
+// instead of starting over. the creation and checksum extensions are supported
+package tus
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clairmont32/httplib"
+	"github.com/clairmont32/httplib/retry"
+)
+
+// Version is the protocol version sent in Tus-Resumable
+const Version = "1.0.0"
+
+// DefaultChunkSize is used when Uploader.ChunkSize is zero
+const DefaultChunkSize = 4 << 20
+
+// statusChecksumMismatch is the tus status for a chunk failing its checksum
+const statusChecksumMismatch = 460
+
+var (
+	// ErrGone is returned when the server no longer knows the upload; it has to be created again
+	ErrGone = errors.New("tus: upload not found")
+	// ErrChecksumMismatch is returned when the server rejected a chunk's checksum
+	ErrChecksumMismatch = errors.New("tus: checksum mismatch")
+)
+
+// Uploader creates and resumes uploads on a tus server
+//
+//	u := tus.Uploader{Endpoint: "https://media.example.com/files/", Checksum: "sha1"}
+//	loc, err := u.Create(ctx, fi.Size(), map[string]string{"filename": fi.Name()})
+//	// persist loc, then on every (re)start:
+//	err = u.Upload(ctx, loc, f, fi.Size())
+type Uploader struct {
+	// Client sends the requests; nil uses httplib.DefaultClient
+	Client *httplib.NewClient
+	// Endpoint is the creation URL uploads are created at
+	Endpoint string
+	// ChunkSize is the most bytes sent per PATCH; zero uses DefaultChunkSize
+	ChunkSize int64
+	// Checksum is the Upload-Checksum algorithm, "sha1", "sha256" or "md5";
+	// empty sends chunks without a checksum
+	Checksum string
+	// Retry paces retries of failed chunks and counts consecutive failures;
+	// the zero value uses retry.Default()
+	Retry retry.Policy
+	// Headers are added to every request, e.g. for authentication
+	Headers []httplib.Headers
+	// Progress is called after every confirmed chunk
+	Progress httplib.ProgressFunc
+}
+
+// Create registers an upload of size bytes and returns its URL. metadata is
+// sent base64 encoded in Upload-Metadata, e.g. {"filename": "capture.mp4"}
+func (u *Uploader) Create(ctx context.Context, size int64, metadata map[string]string) (string, error) {
+	req, err := u.request(ctx, http.MethodPost, u.Endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Upload-Length", strconv.FormatInt(size, 10))
+	if len(metadata) > 0 {
+		req.Header.Set("Upload-Metadata", encodeMetadata(metadata))
+	}
+
+	resp, err := u.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer httplib.DrainBody(resp)
+	if resp.StatusCode != http.StatusCreated {
+		return "", statusError(resp)
+	}
+	loc, err := resp.Location()
+	if err != nil {
+		return "", fmt.Errorf("tus: creation response: %w", err)
+	}
+	return loc.String(), nil
+}
+
+// Offset returns how many bytes of the upload the server has and its total
+// length, which is -1 when the length was deferred
+func (u *Uploader) Offset(ctx context.Context, uploadURL string) (offset, length int64, err error) {
+	req, err := u.request(ctx, http.MethodHead, uploadURL, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Cache-Control", "no-store")
+
+	resp, err := u.do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer httplib.DrainBody(resp)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return 0, 0, statusError(resp)
+	}
+	if offset, err = headerInt(resp, "Upload-Offset"); err != nil {
+		return 0, 0, err
+	}
+	length = -1
+	if resp.Header.Get("Upload-Length") != "" {
+		if length, err = headerInt(resp, "Upload-Length"); err != nil {
+			return 0, 0, err
+		}
+	}
+	return offset, length, nil
+}
+
+// Upload sends r from the offset the server has up to size in chunks. failed
+// chunks are retried after asking the server for its offset again, until
+// Retry gives up on consecutive failures. calling Upload again later resumes
+func (u *Uploader) Upload(ctx context.Context, uploadURL string, r io.ReaderAt, size int64) error {
+	if _, err := newHash(u.Checksum); err != nil {
+		return err
+	}
+	policy := u.Retry
+	if policy.MaxAttempts == 0 {
+		policy = retry.Default()
+	}
+	chunk := u.ChunkSize
+	if chunk <= 0 {
+		chunk = DefaultChunkSize
+	}
+
+	offset, failures := int64(-1), 0
+	for offset < size {
+		var err error
+		if offset < 0 {
+			offset, _, err = u.Offset(ctx, uploadURL)
+		} else {
+			n := chunk
+			if size-offset < n {
+				n = size - offset
+			}
+			offset, err = u.patch(ctx, uploadURL, io.NewSectionReader(r, offset, n), offset, n)
+		}
+		if err == nil {
+			failures = 0
+			if u.Progress != nil {
+				u.Progress(offset, size)
+			}
+			continue
+		}
+
+		failures++
+		if !retryable(ctx, err) || failures >= policy.Attempts() {
+			return err
+		}
+		t := time.NewTimer(policy.Delay(failures))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		// the server may have stored part of the chunk; continue from its offset
+		offset = -1
+	}
+	return nil
+}
+
+// patch sends n bytes from body at offset and returns the offset the server confirmed
+func (u *Uploader) patch(ctx context.Context, uploadURL string, body *io.SectionReader, offset, n int64) (int64, error) {
+	req, err := u.request(ctx, http.MethodPatch, uploadURL, body)
+	if err != nil {
+		return -1, err
+	}
+	req.ContentLength = n
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(body, 0, n)), nil
+	}
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	if u.Checksum != "" {
+		sum, err := checksum(u.Checksum, io.NewSectionReader(body, 0, n))
+		if err != nil {
+			return -1, err
+		}
+		req.Header.Set("Upload-Checksum", u.Checksum+" "+sum)
+	}
+
+	resp, err := u.do(req)
+	if err != nil {
+		return -1, err
+	}
+	defer httplib.DrainBody(resp)
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusOK:
+		next, err := headerInt(resp, "Upload-Offset")
+		if err != nil {
+			return -1, err
+		}
+		if next <= offset {
+			return -1, fmt.Errorf("tus: server did not advance past offset %d", offset)
+		}
+		return next, nil
+	case statusChecksumMismatch:
+		return -1, ErrChecksumMismatch
+	default:
+		return -1, statusError(resp)
+	}
+}
+
+func (u *Uploader) request(ctx context.Context, method, rawURL string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Tus-Resumable", Version)
+	for _, h := range u.Headers {
+		h.AddHeader(req)
+	}
+	return req, nil
+}
+
+func (u *Uploader) do(req *http.Request) (*http.Response, error) {
+	if u.Client == nil {
+		resp, _, err := httplib.DefaultClient(req)
+		return resp, err
+	}
+	resp, _, err := u.Client.DoRequest(req)
+	return resp, err
+}
+
+// retryable reports whether a failed chunk or offset query is worth another try
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrGone) {
+		return false
+	}
+	var serr *httplib.StatusError
+	if errors.As(err, &serr) {
+		return serr.StatusCode == http.StatusConflict || serr.StatusCode == http.StatusTooManyRequests || serr.StatusCode >= 500
+	}
+	return true
+}
+
+// statusError reports an unexpected response, mapping 404 and 410 to ErrGone
+func statusError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return ErrGone
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	return &httplib.StatusError{StatusCode: resp.StatusCode, Status: resp.Status, ContentType: resp.Header.Get("Content-Type"), Body: body}
+}
+
+func headerInt(resp *http.Response, name string) (int64, error) {
+	n, err := strconv.ParseInt(resp.Header.Get(name), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("tus: invalid %s header %q", name, resp.Header.Get(name))
+	}
+	return n, nil
+}
+
+// encodeMetadata formats Upload-Metadata with keys sorted for stable requests
+func encodeMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + " " + base64.StdEncoding.EncodeToString([]byte(metadata[k]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "":
+		return nil, nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "md5":
+		return md5.New(), nil
+	default:
+		return nil, fmt.Errorf("tus: unsupported checksum algorithm %q", algorithm)
+	}
+}
+
+func checksum(algorithm string, r io.Reader) (string, error) {
+	h, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
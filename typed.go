@@ -0,0 +1,85 @@
+package httplib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// GetJSON fetches url through client and decodes the JSON body into a T.
+// a nil client uses the default client. the Response is returned whenever one
+// arrived, with a *StatusError for non-2xx statuses
+//
+//	user, _, err := GetJSON[User](nil, "https://api.example.com/users/1", nil)
+func GetJSON[T any](client *NewClient, url string, headers []Headers) (T, *Response, error) {
+	return GetJSONWithContext[T](context.Background(), client, url, headers)
+}
+
+// GetJSONWithContext is GetJSON bound to ctx
+func GetJSONWithContext[T any](ctx context.Context, client *NewClient, url string, headers []Headers) (T, *Response, error) {
+	return sendTyped[T](ctx, client, http.MethodGet, url, nil, jsonCodec, headers)
+}
+
+// PostJSON encodes body as JSON, posts it to url through client and decodes
+// the JSON response into a U. a nil client uses the default client
+func PostJSON[T, U any](client *NewClient, url string, body T, headers []Headers) (U, *Response, error) {
+	return PostJSONWithContext[T, U](context.Background(), client, url, body, headers)
+}
+
+// PostJSONWithContext is PostJSON bound to ctx
+func PostJSONWithContext[T, U any](ctx context.Context, client *NewClient, url string, body T, headers []Headers) (U, *Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		var zero U
+		return zero, nil, fmt.Errorf("encoding request body: %w", err)
+	}
+	return sendTyped[U](ctx, client, http.MethodPost, url, payload, jsonCodec, headers)
+}
+
+// typedCodec is the wire format of the typed helpers
+type typedCodec struct {
+	mediaType string
+	decode    func(contentType string, body []byte, v interface{}) error
+}
+
+var jsonCodec = typedCodec{
+	mediaType: "application/json",
+	decode:    func(_ string, body []byte, v interface{}) error { return json.Unmarshal(body, v) },
+}
+
+// sendTyped sends payload and decodes the response into a T. empty bodies,
+// e.g. of a 204, leave T at its zero value
+func sendTyped[T any](ctx context.Context, client *NewClient, method, url string, payload []byte, codec typedCodec, headers []Headers) (T, *Response, error) {
+	var out T
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+	if err != nil {
+		return out, nil, err
+	}
+	req.Header.Set("Accept", codec.mediaType)
+	if payload != nil {
+		req.Header.Set("Content-Type", codec.mediaType)
+	}
+	for _, h := range headers {
+		h.AddHeader(req)
+	}
+
+	if client == nil {
+		client = sharedClient()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return out, nil, err
+	}
+	if err := resp.Err(); err != nil {
+		return out, resp, err
+	}
+	if len(bytes.TrimSpace(resp.Body)) == 0 {
+		return out, resp, nil
+	}
+	if err := codec.decode(resp.Header.Get("Content-Type"), resp.Body, &out); err != nil {
+		return out, resp, fmt.Errorf("decoding response: %w", err)
+	}
+	return out, resp, nil
+}
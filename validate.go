@@ -0,0 +1,110 @@
+package httplib
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ValidationError lists every misconfiguration found by Validate
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate is a strict pass over the client settings that fails fast on
+// configurations that work but are almost always mistakes: no timeout,
+// certificate checks disabled outside Dev, retries without backoff, or invalid
+// profiles and live config.
+// call it once after building the client; DoRequest does not run it
+func (c NewClient) Validate() error {
+	var problems []string
+
+	timeout := c.Timeout
+	if c.Live != nil {
+		cfg := c.Live.Load()
+		if err := cfg.validate(); err != nil {
+			problems = append(problems, "live config: "+err.Error())
+		}
+		if cfg.Timeout > 0 {
+			timeout = cfg.Timeout
+		}
+	}
+	if timeout <= 0 {
+		problems = append(problems, "no timeout set; requests can hang forever")
+	}
+
+	if t, ok := c.Transport.(*http.Transport); ok && !c.Dev {
+		if t.TLSClientConfig != nil && t.TLSClientConfig.InsecureSkipVerify {
+			problems = append(problems, "InsecureSkipVerify is enabled outside Dev mode")
+		}
+	}
+
+	if c.Retry != nil && c.Retry.Attempts() > 1 && c.Retry.Base <= 0 {
+		problems = append(problems, "retry policy retries without any backoff")
+	}
+
+	for i, mw := range c.Middleware {
+		if mw == nil {
+			problems = append(problems, fmt.Sprintf("middleware %d is nil", i))
+		}
+	}
+	for name, fn := range c.HeaderFuncs {
+		if fn == nil {
+			problems = append(problems, fmt.Sprintf("header func for %s is nil", name))
+		}
+	}
+
+	for name, p := range c.Profiles {
+		if p.Timeout < 0 {
+			problems = append(problems, fmt.Sprintf("profile %q: negative timeout %s", name, p.Timeout))
+		}
+		for _, h := range p.Headers {
+			if h.Key == "" {
+				problems = append(problems, fmt.Sprintf("profile %q: header with empty name", name))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Validate checks the request is well formed before it is sent: the base URL,
+// or the endpoint when there is no base, needs a scheme and host, and the
+// method must be a valid token
+func (r FormRequest) Validate() error {
+	var problems []string
+
+	if _, err := JoinURL(r.BaseURL, r.Endpoint); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if r.Method != "" && strings.IndexFunc(r.Method, notTokenChar) >= 0 {
+		problems = append(problems, fmt.Sprintf("invalid method %q", r.Method))
+	}
+	if r.Payload != nil && r.Template != nil {
+		problems = append(problems, "both Payload and Template are set")
+	}
+	if r.Body != nil && (r.Payload != nil || r.Template != nil) {
+		problems = append(problems, "Body is ignored when Payload or Template is set")
+	}
+	if r.Form != nil && (r.Payload != nil || r.Template != nil || r.Body != nil) {
+		problems = append(problems, "Form is ignored when Payload, Template or Body is set")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// notTokenChar reports characters not allowed in an HTTP token such as a method
+func notTokenChar(c rune) bool {
+	return c <= ' ' || c >= 0x7f || strings.ContainsRune(`()<>@,;:\"/[]?={}`, c)
+}
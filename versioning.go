@@ -0,0 +1,159 @@
+package httplib
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Versioning selects the API version of every request a client sends and
+// watches responses for deprecation notices, e.g.
+//
+//	c := NewClient{Timeout: 10 * time.Second, Versioning: &Versioning{Header: "X-API-Version", Value: "2024-01-01"}}
+//	c := NewClient{Timeout: 10 * time.Second, Versioning: &Versioning{MediaType: "application/vnd.example.v2+json"}}
+//
+// requests that already carry the header or an Accept value are left alone
+type Versioning struct {
+	// Header and Value name the version through a request header
+	Header string
+	Value  string
+	// MediaType names the version through Accept media-type versioning
+	MediaType string
+
+	// OnDeprecated is called for every response announcing a deprecation or
+	// sunset. nil logs a warning once per method and endpoint
+	OnDeprecated func(Deprecation)
+
+	warned sync.Map // method + url without query -> struct{}
+}
+
+// Deprecation is announced by the Deprecation, Sunset and Link headers of a response
+type Deprecation struct {
+	Method string
+	URL    string
+	// Date is when the endpoint was or will be deprecated; zero when the
+	// header only says it is
+	Date time.Time
+	// Sunset is when the endpoint stops working; zero when not announced
+	Sunset time.Time
+	// Link points to documentation from a Link with rel="deprecation" or "sunset"
+	Link string
+}
+
+// ParseDeprecation reads the deprecation headers of h. ok is false when the
+// response announces neither a deprecation nor a sunset
+func ParseDeprecation(h http.Header) (d Deprecation, ok bool) {
+	if v := strings.TrimSpace(h.Get("Deprecation")); v != "" && !strings.EqualFold(v, "false") {
+		ok = true
+		d.Date = parseDeprecationDate(v)
+	}
+	if v := strings.TrimSpace(h.Get("Sunset")); v != "" {
+		if t, err := http.ParseTime(v); err == nil {
+			ok = true
+			d.Sunset = t
+		}
+	}
+	if !ok {
+		return Deprecation{}, false
+	}
+	d.Link = deprecationLink(h.Values("Link"))
+	return d, true
+}
+
+// parseDeprecationDate reads "@<unix seconds>" as in RFC 9745, or the HTTP
+// date of earlier drafts. "true" and anything else give the zero time
+func parseDeprecationDate(v string) time.Time {
+	if strings.HasPrefix(v, "@") {
+		if secs, err := strconv.ParseInt(v[1:], 10, 64); err == nil {
+			return time.Unix(secs, 0)
+		}
+		return time.Time{}
+	}
+	t, _ := http.ParseTime(v)
+	return t
+}
+
+// deprecationLink returns the target of the first deprecation link, or sunset link
+func deprecationLink(values []string) string {
+	var sunset string
+	for _, v := range values {
+		for _, link := range strings.Split(v, ",") {
+			target, params, found := strings.Cut(link, ";")
+			if !found {
+				continue
+			}
+			target = strings.Trim(strings.TrimSpace(target), "<>")
+			for _, p := range strings.Split(params, ";") {
+				k, val, _ := strings.Cut(p, "=")
+				if !strings.EqualFold(strings.TrimSpace(k), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(val), `"`)) {
+					switch strings.ToLower(rel) {
+					case "deprecation":
+						return target
+					case "sunset":
+						if sunset == "" {
+							sunset = target
+						}
+					}
+				}
+			}
+		}
+	}
+	return sunset
+}
+
+// apply returns req with the version header or Accept media type set,
+// leaving the caller's request untouched
+func (v *Versioning) apply(req *http.Request) *http.Request {
+	setHeader := v.Header != "" && req.Header.Get(v.Header) == ""
+	setAccept := v.MediaType != "" && req.Header.Get("Accept") == ""
+	if !setHeader && !setAccept {
+		return req
+	}
+	r := req.Clone(req.Context())
+	if setHeader {
+		r.Header.Set(v.Header, v.Value)
+	}
+	if setAccept {
+		r.Header.Set("Accept", v.MediaType)
+	}
+	return r
+}
+
+// deprecation returns the deprecation announced by resp to req
+func deprecation(req *http.Request, resp *http.Response) (Deprecation, bool) {
+	d, ok := ParseDeprecation(resp.Header)
+	if !ok {
+		return Deprecation{}, false
+	}
+	u := *req.URL
+	u.RawQuery = ""
+	d.Method, d.URL = req.Method, u.String()
+	return d, true
+}
+
+// report passes d to OnDeprecated or logs it once per endpoint
+func (v *Versioning) report(d Deprecation, l Logger) {
+	if v.OnDeprecated != nil {
+		v.OnDeprecated(d)
+		return
+	}
+	if _, seen := v.warned.LoadOrStore(d.Method+" "+d.URL, struct{}{}); seen {
+		return
+	}
+	kv := []interface{}{"method", d.Method, "url", redactString(d.URL)}
+	if !d.Date.IsZero() {
+		kv = append(kv, "deprecated", d.Date.UTC().Format(time.RFC3339))
+	}
+	if !d.Sunset.IsZero() {
+		kv = append(kv, "sunset", d.Sunset.UTC().Format(time.RFC3339))
+	}
+	if d.Link != "" {
+		kv = append(kv, "link", d.Link)
+	}
+	l.Warn("endpoint is deprecated", kv...)
+}
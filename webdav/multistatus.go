@@ -0,0 +1,114 @@
+package webdav
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clairmont32/httplib"
+)
+
+// Resource is one entry of a 207 Multi-Status response
+type Resource struct {
+	// Href is as sent by the server, percent-encoded; url.PathUnescape it
+	// before passing it back to Client methods as a path
+	Href string
+	// Status is the status of the entry, or of its found properties
+	Status int
+
+	IsCollection  bool
+	DisplayName   string
+	ContentType   string
+	ContentLength int64
+	ETag          string
+	LastModified  time.Time
+
+	// Props holds the text of every property returned with a 2xx status,
+	// keyed by namespace and local name
+	Props map[xml.Name]string
+}
+
+type multistatus struct {
+	Responses []msResponse `xml:"DAV: response"`
+}
+
+type msResponse struct {
+	Href      string       `xml:"DAV: href"`
+	Status    string       `xml:"DAV: status"`
+	Propstats []msPropstat `xml:"DAV: propstat"`
+}
+
+type msPropstat struct {
+	Status string `xml:"DAV: status"`
+	Prop   struct {
+		Props []msProp `xml:",any"`
+	} `xml:"DAV: prop"`
+}
+
+type msProp struct {
+	XMLName xml.Name
+	Inner   []byte `xml:",innerxml"`
+	Text    string `xml:",chardata"`
+}
+
+// ParseMultistatus decodes a 207 Multi-Status body, converting its charset
+// like httplib.DecodeXML
+func ParseMultistatus(contentType string, body []byte) ([]Resource, error) {
+	var ms multistatus
+	if err := httplib.DecodeXML(contentType, body, &ms); err != nil {
+		return nil, fmt.Errorf("webdav: multistatus: %w", err)
+	}
+	resources := make([]Resource, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		res := Resource{Href: strings.TrimSpace(r.Href), Status: parseStatusLine(r.Status), Props: make(map[xml.Name]string)}
+		for _, ps := range r.Propstats {
+			status := parseStatusLine(ps.Status)
+			if res.Status == 0 || status < 300 {
+				res.Status = status
+			}
+			if status < 200 || status >= 300 {
+				continue
+			}
+			for _, p := range ps.Prop.Props {
+				res.setProp(p)
+			}
+		}
+		resources = append(resources, res)
+	}
+	return resources, nil
+}
+
+func (r *Resource) setProp(p msProp) {
+	text := strings.TrimSpace(p.Text)
+	r.Props[p.XMLName] = text
+	if p.XMLName.Space != "DAV:" {
+		return
+	}
+	switch p.XMLName.Local {
+	case "resourcetype":
+		r.IsCollection = strings.Contains(string(p.Inner), "collection")
+	case "displayname":
+		r.DisplayName = text
+	case "getcontenttype":
+		r.ContentType = text
+	case "getcontentlength":
+		r.ContentLength, _ = strconv.ParseInt(text, 10, 64)
+	case "getetag":
+		r.ETag = text
+	case "getlastmodified":
+		r.LastModified, _ = http.ParseTime(text)
+	}
+}
+
+// parseStatusLine returns the code of a status line like "HTTP/1.1 404 Not Found", or 0
+func parseStatusLine(line string) int {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return 0
+	}
+	code, _ := strconv.Atoi(fields[1])
+	return code
+}
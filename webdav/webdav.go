@@ -0,0 +1,209 @@
+// Package webdav adds the WebDAV methods of RFC 4918 on top of httplib for
+// storage appliances that expose files over WebDAV: PROPFIND with a minimal
+// Multi-Status parser, MKCOL, MOVE and COPY
+package webdav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/clairmont32/httplib"
+)
+
+// WebDAV methods
+const (
+	MethodPropfind = "PROPFIND"
+	MethodMkcol    = "MKCOL"
+	MethodMove     = "MOVE"
+	MethodCopy     = "COPY"
+)
+
+// Depth is the value of the Depth header
+type Depth string
+
+// Depths of PROPFIND and COPY
+const (
+	DepthZero     Depth = "0"
+	DepthOne      Depth = "1"
+	DepthInfinity Depth = "infinity"
+)
+
+// propfindAll asks for every property
+const propfindAll = `<?xml version="1.0" encoding="utf-8"?><propfind xmlns="DAV:"><allprop/></propfind>`
+
+// Client talks WebDAV to the server at BaseURL; paths are relative to it and
+// are escaped, so "music/track #1.mp3" names that file
+type Client struct {
+	// BaseURL is the root of the WebDAV share, e.g. "https://nas.example.com/dav/"
+	BaseURL string
+	// Client sends the requests; nil uses httplib.DefaultClient
+	Client *httplib.NewClient
+	// Headers are added to every request, e.g. for authentication
+	Headers []httplib.Headers
+}
+
+// MultiStatusError is returned when some resources of a MOVE, COPY or MKCOL failed
+type MultiStatusError struct {
+	Failed []Resource
+}
+
+func (e *MultiStatusError) Error() string {
+	hrefs := make([]string, len(e.Failed))
+	for i, r := range e.Failed {
+		hrefs[i] = fmt.Sprintf("%s (%d)", r.Href, r.Status)
+	}
+	return "webdav: failed for " + strings.Join(hrefs, ", ")
+}
+
+// Propfind lists the properties of path and, with DepthOne or DepthInfinity,
+// of its members. servers often refuse DepthInfinity
+func (c *Client) Propfind(ctx context.Context, path string, depth Depth) ([]Resource, error) {
+	req, err := c.request(ctx, MethodPropfind, path, strings.NewReader(propfindAll))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `application/xml; charset="utf-8"`)
+	req.Header.Set("Depth", string(depth))
+
+	resp, body, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, statusError(resp, body)
+	}
+	return ParseMultistatus(resp.Header.Get("Content-Type"), body)
+}
+
+// Mkcol creates the collection path; its parent must exist
+func (c *Client) Mkcol(ctx context.Context, path string) error {
+	req, err := c.request(ctx, MethodMkcol, path, nil)
+	if err != nil {
+		return err
+	}
+	return c.expect(req, http.StatusCreated)
+}
+
+// Move renames src to dst, replacing an existing dst only with overwrite
+func (c *Client) Move(ctx context.Context, src, dst string, overwrite bool) error {
+	req, err := c.transfer(ctx, MethodMove, src, dst, overwrite)
+	if err != nil {
+		return err
+	}
+	return c.expect(req, http.StatusCreated, http.StatusNoContent)
+}
+
+// Copy copies src to dst, with members down to depth for collections,
+// replacing an existing dst only with overwrite
+func (c *Client) Copy(ctx context.Context, src, dst string, depth Depth, overwrite bool) error {
+	req, err := c.transfer(ctx, MethodCopy, src, dst, overwrite)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Depth", string(depth))
+	return c.expect(req, http.StatusCreated, http.StatusNoContent)
+}
+
+func (c *Client) transfer(ctx context.Context, method, src, dst string, overwrite bool) (*http.Request, error) {
+	req, err := c.request(ctx, method, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	dest, err := c.resolve(dst)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Destination", dest)
+	req.Header.Set("Overwrite", "F")
+	if overwrite {
+		req.Header.Set("Overwrite", "T")
+	}
+	return req, nil
+}
+
+// expect sends req and fails unless it gets one of the statuses; a 207 is
+// checked for failed members
+func (c *Client) expect(req *http.Request, statuses ...int) error {
+	resp, body, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusMultiStatus {
+		resources, err := ParseMultistatus(resp.Header.Get("Content-Type"), body)
+		if err != nil {
+			return err
+		}
+		var failed []Resource
+		for _, r := range resources {
+			if r.Status >= 300 {
+				failed = append(failed, r)
+			}
+		}
+		if len(failed) > 0 {
+			return &MultiStatusError{Failed: failed}
+		}
+		return nil
+	}
+	for _, s := range statuses {
+		if resp.StatusCode == s {
+			return nil
+		}
+	}
+	return statusError(resp, body)
+}
+
+// resolve joins the resource path onto BaseURL. path is a plain file path,
+// not a URL reference; '#', '?', '%' and ':' in names are escaped
+func (c *Client) resolve(path string) (string, error) {
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return "", err
+	}
+	ref := &url.URL{Path: strings.TrimPrefix(path, "/")}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+func (c *Client) request(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	u, err := c.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range c.Headers {
+		h.AddHeader(req)
+	}
+	return req, nil
+}
+
+// do sends req and reads the whole body
+func (c *Client) do(req *http.Request) (*http.Response, []byte, error) {
+	var resp *http.Response
+	var err error
+	if c.Client == nil {
+		resp, _, err = httplib.DefaultClient(req)
+	} else {
+		resp, _, err = c.Client.DoRequest(req)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := httplib.ReadRespBody(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+func statusError(resp *http.Response, body []byte) error {
+	return &httplib.StatusError{StatusCode: resp.StatusCode, Status: resp.Status, ContentType: resp.Header.Get("Content-Type"), Body: body}
+}
@@ -0,0 +1,125 @@
+package httplib
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Window is a daily time range, e.g. 01:00-05:00, as offsets from midnight.
+// an End before Start wraps past midnight
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseWindow parses "HH:MM-HH:MM"
+func ParseWindow(s string) (Window, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return Window{}, fmt.Errorf("window %q: want HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return Window{}, fmt.Errorf("window %q: %w", s, err)
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return Window{}, fmt.Errorf("window %q: %w", s, err)
+	}
+	return Window{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// length returns how long the window stays open
+func (w Window) length() time.Duration {
+	l := w.End - w.Start
+	if l <= 0 {
+		l += 24 * time.Hour
+	}
+	return l
+}
+
+// WindowScheduler holds back requests carrying Tag until one of Windows is open
+// and passes every other request straight to Next. the wait counts against
+// the client timeout, so clients sending bulk work need a timeout that covers it
+//
+//	bulk, _ := ParseWindow("01:00-05:00")
+//	c := NewClient{Transport: &WindowScheduler{Windows: []Window{bulk}}}
+//	c.DoRequest(WithTags(req, "bulk"))
+type WindowScheduler struct {
+	// Next sends the requests; nil uses http.DefaultTransport
+	Next http.RoundTripper
+	// Tag marks deferrable requests; empty means "bulk"
+	Tag string
+	// Windows are the off-peak ranges; with none tagged requests are sent immediately
+	Windows []Window
+	// Location interprets the windows; nil means time.Local
+	Location *time.Location
+}
+
+// RoundTrip waits for an open window for tagged requests, honoring the request context
+func (s *WindowScheduler) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := s.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	tag := s.Tag
+	if tag == "" {
+		tag = "bulk"
+	}
+
+	if hasTag(req, tag) {
+		if wait := time.Until(s.NextOpen(time.Now())); wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-req.Context().Done():
+				t.Stop()
+				if req.Body != nil {
+					_ = req.Body.Close()
+				}
+				return nil, req.Context().Err()
+			}
+		}
+	}
+	return next.RoundTrip(req)
+}
+
+// NextOpen returns t if a window is open at t, otherwise the start of the next window
+func (s *WindowScheduler) NextOpen(t time.Time) time.Time {
+	if len(s.Windows) == 0 {
+		return t
+	}
+	loc := s.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	t = t.In(loc)
+
+	var next time.Time
+	y, m, d := t.Date()
+	// yesterday's window may still be open past midnight
+	for day := -1; day <= 1; day++ {
+		midnight := time.Date(y, m, d+day, 0, 0, 0, 0, loc)
+		for _, w := range s.Windows {
+			start := midnight.Add(w.Start)
+			end := start.Add(w.length())
+			if !t.Before(start) && t.Before(end) {
+				return t
+			}
+			if start.After(t) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+	}
+	return next
+}
@@ -0,0 +1,81 @@
+package httplib
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// GetXML fetches url through client and decodes the XML body into a T,
+// converting from the charset of the Content-Type or the XML declaration.
+// a nil client uses the default client
+func GetXML[T any](client *NewClient, url string, headers []Headers) (T, *Response, error) {
+	return GetXMLWithContext[T](context.Background(), client, url, headers)
+}
+
+// GetXMLWithContext is GetXML bound to ctx
+func GetXMLWithContext[T any](ctx context.Context, client *NewClient, url string, headers []Headers) (T, *Response, error) {
+	return sendTyped[T](ctx, client, http.MethodGet, url, nil, xmlCodec, headers)
+}
+
+// PostXML encodes body as XML, posts it to url through client and decodes
+// the XML response into a U. a nil client uses the default client
+func PostXML[T, U any](client *NewClient, url string, body T, headers []Headers) (U, *Response, error) {
+	return PostXMLWithContext[T, U](context.Background(), client, url, body, headers)
+}
+
+// PostXMLWithContext is PostXML bound to ctx
+func PostXMLWithContext[T, U any](ctx context.Context, client *NewClient, url string, body T, headers []Headers) (U, *Response, error) {
+	payload, err := xml.Marshal(body)
+	if err != nil {
+		var zero U
+		return zero, nil, fmt.Errorf("encoding request body: %w", err)
+	}
+	payload = append([]byte(xml.Header), payload...)
+	return sendTyped[U](ctx, client, http.MethodPost, url, payload, xmlCodec, headers)
+}
+
+var xmlCodec = typedCodec{mediaType: "application/xml", decode: DecodeXML}
+
+// DecodeXML decodes an XML body into v. bodies in another charset than UTF-8
+// are converted using the charset parameter of contentType, or failing that
+// the encoding named in the XML declaration
+func DecodeXML(contentType string, body []byte, v interface{}) error {
+	var r io.Reader = bytes.NewReader(body)
+	_, params, _ := mime.ParseMediaType(contentType)
+	converted := false
+	if cs := params["charset"]; cs != "" && !isUTF8(cs) {
+		enc, err := htmlindex.Get(cs)
+		if err != nil {
+			return fmt.Errorf("unsupported charset %q", cs)
+		}
+		r = enc.NewDecoder().Reader(r)
+		converted = true
+	}
+
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		// the header wins over the declaration, which then names the old charset
+		if converted || isUTF8(label) {
+			return input, nil
+		}
+		enc, err := htmlindex.Get(label)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported charset %q", label)
+		}
+		return enc.NewDecoder().Reader(input), nil
+	}
+	return dec.Decode(v)
+}
+
+func isUTF8(charset string) bool {
+	cs := strings.ToLower(charset)
+	return cs == "utf-8" || cs == "utf8"
+}